@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// RequestToken asks the server for a fresh, not yet authenticated, token.
+func (c *Client) RequestToken(ctx context.Context) (string, error) {
+	var tokenResp struct {
+		Token string `json:"token"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/request_token", "", nil, &tokenResp); err != nil {
+		return "", err
+	}
+	if tokenResp.Token == "" {
+		return "", errors.New("empty token response from server")
+	}
+	return tokenResp.Token, nil
+}
+
+// Ping checks that the server is reachable by requesting a token, which needs no login.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.RequestToken(ctx)
+	return err
+}
+
+// Login requests a new token, authenticates it with creds, and on success uses it for this client.
+func (c *Client) Login(ctx context.Context, creds Credentials) error {
+	return c.authenticate(ctx, "/_login/authenticate", creds)
+}
+
+// Register creates an account with creds, and on success uses the new session for this client.
+func (c *Client) Register(ctx context.Context, creds Credentials) error {
+	return c.authenticate(ctx, "/_login/create", creds)
+}
+
+func (c *Client) authenticate(ctx context.Context, path string, creds Credentials) error {
+	token, err := c.RequestToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.do(ctx, http.MethodPost, path, token, creds, nil); err != nil {
+		return err
+	}
+
+	//the token we authenticated is now our session token
+	c.mu.Lock()
+	c.token = token
+	c.creds = &creds
+	c.mu.Unlock()
+	return nil
+}
+
+/*
+SetCredentials gives the client credentials to log in again with if its
+session expires, for clients whose token was restored rather than logged in.
+*/
+func (c *Client) SetCredentials(creds Credentials) {
+	c.mu.Lock()
+	c.creds = &creds
+	c.mu.Unlock()
+}
+
+// VerifySession checks that the current token is still accepted by the server.
+func (c *Client) VerifySession(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/_game/current", c.Token(), nil, nil)
+}
+
+/*
+reauthenticate logs in again with the stored credentials after staleToken
+was rejected. If another request has already replaced staleToken it does nothing.
+*/
+func (c *Client) reauthenticate(ctx context.Context, staleToken string) error {
+	c.reauthMu.Lock()
+	defer c.reauthMu.Unlock()
+
+	if c.Token() != staleToken {
+		return nil
+	}
+
+	c.mu.RLock()
+	creds := c.creds
+	c.mu.RUnlock()
+	if creds == nil {
+		return ErrUnauthorized
+	}
+
+	if err := c.Login(ctx, *creds); err != nil {
+		return err
+	}
+
+	if c.OnTokenRefresh != nil {
+		c.OnTokenRefresh(c.Token())
+	}
+	return nil
+}
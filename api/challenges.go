@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Colours a challenger can ask to play.
+const (
+	ColourWhite  = "W"
+	ColourBlack  = "B"
+	ColourRandom = "random"
+)
+
+// States of a Challenge.
+const (
+	ChallengePending   = "pending"
+	ChallengeAccepted  = "accepted"
+	ChallengeDeclined  = "declined"
+	ChallengeCancelled = "cancelled"
+	ChallengeExpired   = "expired"
+)
+
+/*
+TimeControl is how long each player has for their moves. The zero value is an
+untimed game. Time can be given back after each move either as a Fischer
+increment or a Bronstein delay.
+*/
+type TimeControl struct {
+	// Base is the seconds each player starts with.
+	Base int `json:"base"`
+	// Increment is the seconds added to a player's clock after each of their moves.
+	Increment int `json:"increment"`
+	// Delay is the most seconds given back after each move, never more than the move took.
+	Delay int `json:"delay,omitempty"`
+}
+
+// String gives the usual short form, eg "10+5", "5 d3" for a delay, or "Untimed".
+func (tc TimeControl) String() string {
+	if tc.Base == 0 {
+		return "Untimed"
+	}
+	base := fmt.Sprintf("%ds", tc.Base)
+	if tc.Base%60 == 0 {
+		base = fmt.Sprintf("%d", tc.Base/60)
+	}
+	if tc.Delay > 0 {
+		return fmt.Sprintf("%s d%d", base, tc.Delay)
+	}
+	return fmt.Sprintf("%s+%d", base, tc.Increment)
+}
+
+// Credit is the time given back to a player's clock after a move that took used.
+func (tc TimeControl) Credit(used time.Duration) time.Duration {
+	credit := time.Duration(tc.Increment) * time.Second
+	if delay := time.Duration(tc.Delay) * time.Second; delay > 0 {
+		credit += min(used, delay)
+	}
+	return credit
+}
+
+/*
+Challenge is an invitation from one player to another to start a game. No game
+exists until the challenged player accepts, at which point GameID is set.
+Challenges nobody answers expire at ExpiresAt.
+*/
+type Challenge struct {
+	ChallengeID int    `json:"challenge_id"`
+	FromID      int    `json:"from_id"`
+	FromName    string `json:"from_name"`
+	ToID        int    `json:"to_id"`
+	ToName      string `json:"to_name"`
+	// Colour is what the challenger will play: ColourWhite, ColourBlack or ColourRandom.
+	Colour      string      `json:"colour"`
+	TimeControl TimeControl `json:"time_control"`
+	Rated       bool        `json:"rated"`
+	State       string      `json:"state"`
+	// ExpiresAt is an RFC 3339 timestamp.
+	ExpiresAt string `json:"expires_at"`
+	GameID    int    `json:"game_id"`
+	// RematchOf is the id of the finished game this challenge is a rematch of, or 0.
+	RematchOf int `json:"rematch_of,omitempty"`
+}
+
+// ChallengeRequest is the body of a new challenge.
+type ChallengeRequest struct {
+	ToID        int         `json:"to_id"`
+	Colour      string      `json:"colour"`
+	TimeControl TimeControl `json:"time_control"`
+	Rated       bool        `json:"rated"`
+	// RematchOf marks the challenge as a rematch of the finished game with this id, which both players played in.
+	RematchOf int `json:"rematch_of,omitempty"`
+}
+
+/*
+Expired reports whether a pending challenge can no longer be accepted, going by
+its own expiry time in case the server hasn't got round to marking it.
+A challenge with no readable expiry never expires here.
+*/
+func (ch *Challenge) Expired(now time.Time) bool {
+	if ch.State == ChallengeExpired {
+		return true
+	}
+	expiresAt, err := time.Parse(time.RFC3339, ch.ExpiresAt)
+	return err == nil && !now.Before(expiresAt)
+}
+
+// SendChallenge challenges another user to a game.
+func (c *Client) SendChallenge(ctx context.Context, challenge ChallengeRequest) (*Challenge, error) {
+	var sent Challenge
+	if err := c.PostJSON(ctx, "/_challenge/new", challenge, &sent); err != nil {
+		return nil, err
+	}
+	return &sent, nil
+}
+
+// Challenges lists the challenges sent to and by the logged in user.
+func (c *Client) Challenges(ctx context.Context) ([]Challenge, error) {
+	var challenges []Challenge
+	if err := c.GetJSON(ctx, "/_challenge/list", &challenges); err != nil {
+		return nil, err
+	}
+	return challenges, nil
+}
+
+// AcceptChallenge accepts a challenge sent to the logged in user, returning the game it starts.
+func (c *Client) AcceptChallenge(ctx context.Context, challengeID int) (*DbGame, error) {
+	var game DbGame
+	if err := c.PostJSON(ctx, fmt.Sprintf("/_challenge/%d/accept", challengeID), nil, &game); err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// DeclineChallenge turns down a challenge sent to the logged in user.
+func (c *Client) DeclineChallenge(ctx context.Context, challengeID int) error {
+	return c.PostJSON(ctx, fmt.Sprintf("/_challenge/%d/decline", challengeID), nil, nil)
+}
+
+// CancelChallenge withdraws a challenge the logged in user sent.
+func (c *Client) CancelChallenge(ctx context.Context, challengeID int) error {
+	return c.PostJSON(ctx, fmt.Sprintf("/_challenge/%d/cancel", challengeID), nil, nil)
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+// MaxChatLength is the longest chat message the server accepts, in bytes.
+const MaxChatLength = 500
+
+// ChatMessage is a message sent in a game's chat.
+type ChatMessage struct {
+	MessageID int    `json:"message_id"`
+	GameID    int    `json:"game_id"`
+	UID       int    `json:"uid"`
+	Username  string `json:"username"`
+	Text      string `json:"text"`
+	// SentAt is an RFC 3339 timestamp.
+	SentAt string `json:"sent_at"`
+}
+
+// ChatHistory fetches a game's chat messages after afterID, oldest first. An afterID of 0 fetches them all.
+func (c *Client) ChatHistory(ctx context.Context, gameID int, afterID int) ([]ChatMessage, error) {
+	var messages []ChatMessage
+	if err := c.GetJSON(ctx, fmt.Sprintf("/_game/%d/chat?after=%d", gameID, afterID), &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+/*
+SendChat posts a message to a game's chat and returns it as stored. Sending
+too often gets an error matching ErrRateLimited.
+*/
+func (c *Client) SendChat(ctx context.Context, gameID int, text string) (*ChatMessage, error) {
+	var message ChatMessage
+	body := map[string]string{"text": text}
+	if err := c.PostJSON(ctx, fmt.Sprintf("/_game/%d/chat", gameID), body, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
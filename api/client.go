@@ -0,0 +1,159 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is how long a single request may take before it is abandoned.
+const DefaultTimeout = 15 * time.Second
+
+// sharedTransport is reused by every Client so connections to the server are pooled.
+var sharedTransport = &http.Transport{
+	Proxy:               http.ProxyFromEnvironment,
+	MaxIdleConns:        10,
+	MaxIdleConnsPerHost: 10,
+	IdleConnTimeout:     90 * time.Second,
+	TLSHandshakeTimeout: 10 * time.Second,
+}
+
+/*
+Client talks to the chess server. It holds the base url and the session
+token, and every endpoint the client uses is a method on it.
+It is safe to use from multiple goroutines.
+*/
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+	// OnTokenRefresh, if set, is called after the client has logged in again by itself because the session expired.
+	OnTokenRefresh func(token string)
+
+	mu    sync.RWMutex
+	token string
+	creds *Credentials
+
+	//held while logging in again so concurrent requests don't each do it
+	reauthMu sync.Mutex
+}
+
+// NewClient creates a client for the server at baseURL with no session token.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		HTTP: &http.Client{
+			Transport: sharedTransport,
+			Timeout:   DefaultTimeout,
+		},
+	}
+}
+
+// Token returns the current session token, or "" if not logged in.
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// SetToken replaces the session token sent with every request.
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+}
+
+/*
+do sends a request to path on the server. If in is not nil it is sent as
+the json body, and if out is not nil a successful response is decoded into it.
+Non 200 responses are returned as a *StatusError.
+*/
+func (c *Client) do(ctx context.Context, method string, path string, token string, in any, out any) error {
+	_, err := c.send(ctx, c.HTTP, method, path, token, in, out)
+	return err
+}
+
+// send is do through httpClient, also returning the response's headers.
+func (c *Client) send(ctx context.Context, httpClient *http.Client, method string, path string, token string, in any, out any) (http.Header, error) {
+	var body io.Reader
+	if in != nil {
+		jsonData, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling request: %v", err)
+		}
+		body = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("token", token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.Header, newStatusError(resp)
+	}
+
+	if out == nil {
+		return resp.Header, nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.Header, fmt.Errorf("error parsing response: %w", err)
+	}
+
+	return resp.Header, nil
+}
+
+/*
+authedDo sends a request with the session token. If the server says the
+session has expired and the client knows the credentials, it logs in again
+and retries the request once.
+*/
+func (c *Client) authedDo(ctx context.Context, method string, path string, in any, out any) error {
+	_, err := c.authedSend(ctx, c.HTTP, method, path, in, out)
+	return err
+}
+
+// authedSend is authedDo through httpClient, also returning the response's headers.
+func (c *Client) authedSend(ctx context.Context, httpClient *http.Client, method string, path string, in any, out any) (http.Header, error) {
+	token := c.Token()
+	header, err := c.send(ctx, httpClient, method, path, token, in, out)
+	if !errors.Is(err, ErrUnauthorized) {
+		return header, err
+	}
+
+	if reauthErr := c.reauthenticate(ctx, token); reauthErr != nil {
+		fmt.Println("could not log in again:", reauthErr)
+		return header, err
+	}
+	return c.send(ctx, httpClient, method, path, c.Token(), in, out)
+}
+
+// GetJSON performs an authenticated GET of path and decodes the response into out.
+func (c *Client) GetJSON(ctx context.Context, path string, out any) error {
+	return c.authedDo(ctx, http.MethodGet, path, nil, out)
+}
+
+// PostJSON performs an authenticated POST of in to path and decodes the response into out.
+func (c *Client) PostJSON(ctx context.Context, path string, in any, out any) error {
+	return c.authedDo(ctx, http.MethodPost, path, in, out)
+}
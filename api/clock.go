@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ClockState is the time left on both clocks of a timed game, as the server reports it.
+type ClockState struct {
+	WhiteMs int64 `json:"white_ms"`
+	BlackMs int64 `json:"black_ms"`
+	// Running is whose clock is counting down, "W" or "B", or empty before the first move and once the game is over.
+	Running string `json:"running"`
+	// ServerTime is when the server read the clocks, as an RFC 3339 timestamp. The running clock has been counted down to then.
+	ServerTime string `json:"server_time"`
+}
+
+// ClockReading is a ClockState along with when it arrived, to work out the time left from.
+type ClockReading struct {
+	ClockState
+	// ReceivedAt is when the response arrived, by the local clock.
+	ReceivedAt time.Time
+	// Latency is the estimated time the response took to arrive, half the round trip.
+	Latency time.Duration
+}
+
+/*
+Clock reads a timed game's clocks. The round trip is timed so the running
+clock can be corrected for the time the response spent on the way.
+Untimed games get an error matching ErrNotFound.
+*/
+func (c *Client) Clock(ctx context.Context, gameID int) (*ClockReading, error) {
+	var reading ClockReading
+	sent := time.Now()
+	if err := c.GetJSON(ctx, fmt.Sprintf("/_game/%d/clock", gameID), &reading.ClockState); err != nil {
+		return nil, err
+	}
+	reading.ReceivedAt = time.Now()
+	reading.Latency = reading.ReceivedAt.Sub(sent) / 2
+	return &reading, nil
+}
+
+// Remaining is the time each player has left at now, by the local clock.
+func (r *ClockReading) Remaining(now time.Time) (white time.Duration, black time.Duration) {
+	white = time.Duration(r.WhiteMs) * time.Millisecond
+	black = time.Duration(r.BlackMs) * time.Millisecond
+
+	elapsed := now.Sub(r.ReceivedAt) + r.Latency
+	switch r.Running {
+	case "W":
+		white -= elapsed
+	case "B":
+		black -= elapsed
+	}
+	return max(white, 0), max(black, 0)
+}
+
+// ServerTimestamp parses ServerTime, giving the zero time if it can't be.
+func (r *ClockReading) ServerTimestamp() time.Time {
+	t, err := time.Parse(time.RFC3339Nano, r.ServerTime)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
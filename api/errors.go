@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors for the kinds of failure the server reports. Use errors.Is to check them.
+var (
+	ErrUnauthorized = errors.New("authentication required")
+	ErrGameOver     = errors.New("the game has ended")
+	ErrValidation   = errors.New("invalid request")
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("too many requests")
+	ErrServer       = errors.New("server error")
+)
+
+// StatusError is returned when the server responds with anything other than 200.
+type StatusError struct {
+	StatusCode int
+	// Message is the server's "error" field if it sent one, else the raw body.
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	if e.StatusCode == http.StatusUnauthorized && e.Message == "" {
+		return ErrUnauthorized.Error()
+	}
+	if e.Message == "" {
+		return fmt.Sprintf("server returned error status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("server returned error status %d: %s", e.StatusCode, e.Message)
+}
+
+// Is lets errors.Is match a StatusError against the sentinel for its status code.
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrGameOver:
+		return e.StatusCode == http.StatusPreconditionFailed
+	case ErrValidation:
+		return e.StatusCode == http.StatusBadRequest ||
+			e.StatusCode == http.StatusUnprocessableEntity
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+func newStatusError(resp *http.Response) *StatusError {
+	bodyBytes, _ := io.ReadAll(resp.Body)
+
+	//prefer the server's own message over the raw body
+	var errBody struct {
+		Error string `json:"error"`
+	}
+	message := strings.TrimSpace(string(bodyBytes))
+	if json.Unmarshal(bodyBytes, &errBody) == nil && errBody.Error != "" {
+		message = errBody.Error
+	}
+
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		Message:    message,
+	}
+}
@@ -0,0 +1,401 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Backoff and polling limits for WatchGame.
+const (
+	minReconnectDelay = 1 * time.Second
+	maxReconnectDelay = 30 * time.Second
+	minPollInterval   = 1 * time.Second
+	maxPollInterval   = 8 * time.Second
+	// longPollWait is how long a server that long polls is asked to hold a poll open for.
+	longPollWait = 25 * time.Second
+)
+
+/*
+GameVersionHeader is sent by servers that long poll with every last move,
+numbering the state of the game so far. Asking for the last move with since set
+to that number and wait set to a number of seconds holds the request open
+until the game moves on, be it a move, an offer, a chat message or the end.
+*/
+const GameVersionHeader = "X-Game-Version"
+
+// errStreamUnsupported means the server has no event stream and WatchGame should poll instead.
+var errStreamUnsupported = errors.New("server does not support event streams")
+
+/*
+streamHTTP is c.HTTP without its timeout, since an event stream stays open for
+the whole game and a long poll for as long as the server holds it.
+*/
+func (c *Client) streamHTTP() *http.Client {
+	return &http.Client{Transport: c.HTTP.Transport}
+}
+
+/*
+GameEvent is something that happened in a game. Exactly one field is set.
+Err is only sent for errors that end the watch, such as ErrGameOver or ErrUnauthorized.
+Reconnected is sent after the connection to the server was lost and came
+back, since moves made in between may never have been delivered.
+*/
+type GameEvent struct {
+	Move *DbMove
+	// Offer is sent when an offer is made and again when it is answered.
+	Offer       *Offer
+	Chat        *ChatMessage
+	Err         error
+	Reconnected bool
+}
+
+/*
+WatchGame delivers moves, offers and chat messages in a game as they happen, until ctx is cancelled
+or the game ends. The returned channel is closed when watching stops.
+
+It listens on the server's /_game/{id}/events server-sent event stream and
+reconnects with backoff if the connection drops. If the server has no such
+stream it falls back to polling the last move, offers and chat. Servers that
+long poll hold each poll until something happens, and others are polled less
+often while the game is quiet, speeding up again as soon as something happens.
+A move may be delivered more than once, so callers should check MIndex, and
+the same goes for chat messages and their MessageID.
+*/
+func (c *Client) WatchGame(ctx context.Context, gameID int) <-chan GameEvent {
+	events := make(chan GameEvent)
+
+	go func() {
+		defer close(events)
+
+		send := func(ev GameEvent) bool {
+			select {
+			case events <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		delay := minReconnectDelay
+		dropped := false
+		for {
+			token := c.Token()
+			connected, err := c.streamGame(ctx, gameID, token, dropped, send)
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, ErrUnauthorized) && c.reauthenticate(ctx, token) == nil {
+				continue
+			}
+			if errors.Is(err, errStreamUnsupported) {
+				fmt.Println("event stream not available, polling for moves instead")
+				c.pollGame(ctx, gameID, send)
+				return
+			}
+			if errors.Is(err, ErrGameOver) || errors.Is(err, ErrUnauthorized) {
+				send(GameEvent{Err: err})
+				return
+			}
+
+			if connected {
+				delay = minReconnectDelay
+			}
+			dropped = true
+			fmt.Println("game event stream dropped, reconnecting in", delay, "error:", err)
+			if !sleepCtx(ctx, delay) {
+				return
+			}
+			delay = min(delay*2, maxReconnectDelay)
+		}
+	}()
+
+	return events
+}
+
+/*
+streamGame reads one connection of the event stream, passing moves to send.
+If reconnecting is set a Reconnected event is sent once the stream is open.
+connected reports whether the stream was opened at all, so the caller knows
+to reset its backoff.
+*/
+func (c *Client) streamGame(ctx context.Context, gameID int, token string, reconnecting bool, send func(GameEvent) bool) (connected bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/_game/%d/events", c.BaseURL, gameID), nil)
+	if err != nil {
+		return false, fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("token", token)
+
+	resp, err := c.streamHTTP().Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return false, errStreamUnsupported
+	default:
+		return false, newStatusError(resp)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return false, errStreamUnsupported
+	}
+
+	if reconnecting && !send(GameEvent{Reconnected: true}) {
+		return true, context.Canceled
+	}
+
+	//server sent events are "field: value" lines, with a blank line ending each event
+	var eventType, data string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatchEvent(eventType, data, send); err != nil {
+				return true, err
+			}
+			eventType, data = "", ""
+		case strings.HasPrefix(line, "event:"):
+			eventType = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data != "" {
+				data += "\n"
+			}
+			data += strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return true, err
+	}
+	return true, errors.New("event stream closed by server")
+}
+
+// dispatchEvent handles one complete server sent event.
+func dispatchEvent(eventType string, data string, send func(GameEvent) bool) error {
+	switch eventType {
+	case "move":
+		var move DbMove
+		if err := json.Unmarshal([]byte(data), &move); err != nil {
+			fmt.Println("error parsing move event:", err)
+			return nil
+		}
+		if !send(GameEvent{Move: &move}) {
+			return context.Canceled
+		}
+	case "offer":
+		var offer Offer
+		if err := json.Unmarshal([]byte(data), &offer); err != nil {
+			fmt.Println("error parsing offer event:", err)
+			return nil
+		}
+		if !send(GameEvent{Offer: &offer}) {
+			return context.Canceled
+		}
+	case "chat":
+		var message ChatMessage
+		if err := json.Unmarshal([]byte(data), &message); err != nil {
+			fmt.Println("error parsing chat event:", err)
+			return nil
+		}
+		if !send(GameEvent{Chat: &message}) {
+			return context.Canceled
+		}
+	case "game_over":
+		return ErrGameOver
+	}
+	//anything else, such as keepalives, is ignored
+	return nil
+}
+
+/*
+pollGame is the fallback for servers without an event stream. It polls the
+game with a gamePoller, straight after the last poll when the server held it
+open until something happened, otherwise slowing down while nothing changes.
+*/
+func (c *Client) pollGame(ctx context.Context, gameID int, send func(GameEvent) bool) {
+	poller := newGamePoller(c, gameID)
+	interval := minPollInterval
+	failing := false
+
+	for sleepCtx(ctx, interval) {
+		started := time.Now()
+		active, err := poller.poll(ctx, send)
+		if errors.Is(err, ErrGameOver) || errors.Is(err, ErrUnauthorized) {
+			send(GameEvent{Err: err})
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			fmt.Println("error polling game:", err)
+			failing = true
+			interval = min(max(interval*2, minPollInterval), maxPollInterval)
+			continue
+		}
+
+		if failing {
+			failing = false
+			if !send(GameEvent{Reconnected: true}) {
+				return
+			}
+		}
+
+		//a poll the server held was waiting for news already, so ask again straight away
+		held := poller.version > 0 && (active || time.Since(started) >= minPollInterval)
+		switch {
+		case held:
+			interval = 0
+		case active:
+			interval = minPollInterval
+		default:
+			//nothing new means check less often
+			interval = min(max(interval*2, minPollInterval), maxPollInterval)
+		}
+	}
+}
+
+/*
+gamePoller polls the last move, the game's offers and new chat messages,
+remembering what it has sent so each poll only sends what changed.
+Offers and chat are left out for servers that don't have them.
+*/
+type gamePoller struct {
+	client *Client
+	gameID int
+
+	//the last move sent, compared whole as MIndex is used again after a takeback
+	lastMove *DbMove
+	//the game's version from a server that long polls, 0 until one has been seen
+	version     int
+	offerStates map[int]string
+	pollOffers  bool
+	lastChatID  int
+	pollChat    bool
+}
+
+func newGamePoller(c *Client, gameID int) *gamePoller {
+	return &gamePoller{
+		client:      c,
+		gameID:      gameID,
+		offerStates: make(map[int]string),
+		pollOffers:  true,
+		pollChat:    true,
+	}
+}
+
+/*
+poll checks the game once, passing what changed to send. active reports
+whether anything had. If send gives up, poll returns context.Canceled.
+*/
+func (p *gamePoller) poll(ctx context.Context, send func(GameEvent) bool) (active bool, err error) {
+	move, version, err := p.client.waitForMove(ctx, p.gameID, p.version, longPollWait)
+	if err != nil {
+		return false, err
+	}
+	p.version = version
+	var offers []Offer
+	if p.pollOffers {
+		offers, err = p.client.Offers(ctx, p.gameID)
+		if errors.Is(err, ErrNotFound) {
+			fmt.Println("server has no offers, not polling for them")
+			p.pollOffers = false
+		} else if err != nil {
+			return false, err
+		}
+	}
+	var messages []ChatMessage
+	if p.pollChat {
+		messages, err = p.client.ChatHistory(ctx, p.gameID, p.lastChatID)
+		if errors.Is(err, ErrNotFound) {
+			fmt.Println("server has no chat, not polling for it")
+			p.pollChat = false
+		} else if err != nil {
+			return false, err
+		}
+	}
+
+	for i := range offers {
+		offer := offers[i]
+		if p.offerStates[offer.OfferID] == offer.State {
+			continue
+		}
+		p.offerStates[offer.OfferID] = offer.State
+		active = true
+		//after a takeback the next move can look like the last one sent, so it is sent whatever it is
+		if offer.Kind == TakebackOffer && offer.State == OfferAccepted {
+			p.lastMove = nil
+		}
+		if !send(GameEvent{Offer: &offer}) {
+			return active, context.Canceled
+		}
+	}
+
+	for i := range messages {
+		message := messages[i]
+		p.lastChatID = max(p.lastChatID, message.MessageID)
+		active = true
+		if !send(GameEvent{Chat: &message}) {
+			return active, context.Canceled
+		}
+	}
+
+	if move != nil && (p.lastMove == nil || *move != *p.lastMove) {
+		p.lastMove = move
+		active = true
+		if !send(GameEvent{Move: move}) {
+			return active, context.Canceled
+		}
+	}
+
+	return active, nil
+}
+
+/*
+waitForMove is LastMove for long polling. Once a server has given a version,
+passing it as since asks the server to wait for the game to move on from it,
+for at most wait. The version the game is at is returned along with the move,
+or 0 from servers that don't long poll, which answer straight away.
+*/
+func (c *Client) waitForMove(ctx context.Context, gameID int, since int, wait time.Duration) (*DbMove, int, error) {
+	path := fmt.Sprintf("/_game/%d/last_move", gameID)
+	if since > 0 {
+		path += fmt.Sprintf("?since=%d&wait=%d", since, int(wait.Seconds()))
+	}
+	//held open for up to wait, on top of the time any request may take
+	ctx, cancel := context.WithTimeout(ctx, wait+DefaultTimeout)
+	defer cancel()
+
+	var move DbMove
+	header, err := c.authedSend(ctx, c.streamHTTP(), http.MethodGet, path, nil, &move)
+	if err != nil {
+		return nil, 0, err
+	}
+	version, _ := strconv.Atoi(header.Get(GameVersionHeader))
+	if move.GameID == 0 {
+		return nil, version, nil
+	}
+	return &move, version, nil
+}
+
+// sleepCtx waits for d, returning false early if ctx is cancelled.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
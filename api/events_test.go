@@ -0,0 +1,315 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jjj333-p/chess-fe-go/api"
+	"github.com/jjj333-p/chess-fe-go/api/fakeserver"
+)
+
+/*
+watchedGame starts a game on a fake server between white and black, returning
+the server, their clients and the game.
+*/
+func watchedGame(t *testing.T) (*fakeserver.Server, *api.Client, *api.Client, *api.DbGame) {
+	t.Helper()
+	ctx := context.Background()
+	s := fakeserver.New()
+	t.Cleanup(s.Close)
+	s.AddUser("white", "pw")
+	black := s.AddUser("black", "pw")
+
+	clients := make([]*api.Client, 2)
+	for i, username := range []string{"white", "black"} {
+		clients[i] = api.NewClient(s.URL)
+		if err := clients[i].Login(ctx, api.Credentials{Username: username, Password: "pw"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	game, err := clients[0].NewGame(ctx, black.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, clients[0], clients[1], game
+}
+
+// play makes moves in algebraic squares, like "e2e4", taking turns from white.
+func play(t *testing.T, whiteClient *api.Client, blackClient *api.Client, gameID int, blackFirst bool, moves ...string) {
+	t.Helper()
+	clients := []*api.Client{whiteClient, blackClient}
+	if blackFirst {
+		clients[0], clients[1] = blackClient, whiteClient
+	}
+	for i, m := range moves {
+		req := api.MoveRequest{
+			PieceID: "P",
+			MFrom:   [2]int{int(m[0] - 'a'), int(m[1] - '1')},
+			MTo:     [2]int{int(m[2] - 'a'), int(m[3] - '1')},
+		}
+		if err := clients[i%2].MakeMove(context.Background(), gameID, req); err != nil {
+			t.Fatalf("%s: %v", m, err)
+		}
+	}
+}
+
+// collect is a send func for WatchGame's events that keeps them in events.
+func collect(events *[]api.GameEvent) func(api.GameEvent) bool {
+	return func(ev api.GameEvent) bool {
+		*events = append(*events, ev)
+		return true
+	}
+}
+
+// movesIn lists the moves among events as their from and to squares, numbered file*8+rank.
+func movesIn(events []api.GameEvent) [][2]int {
+	var moves [][2]int
+	for _, ev := range events {
+		if ev.Move != nil {
+			moves = append(moves, [2]int{ev.Move.MFrom, ev.Move.MTo})
+		}
+	}
+	return moves
+}
+
+func TestPollTakebackThenMove(t *testing.T) {
+	ctx := context.Background()
+	s, whiteClient, blackClient, game := watchedGame(t)
+	//a quiet poll would otherwise be held open until something happens
+	s.NoLongPoll = true
+	poll := api.NewPoller(whiteClient, game.GameID)
+
+	play(t, whiteClient, blackClient, game.GameID, false, "e2e4", "e7e5")
+	var events []api.GameEvent
+	if _, err := poll(collect(&events)); err != nil {
+		t.Fatal(err)
+	}
+	//e7 is file 4 rank 6
+	if moves := movesIn(events); len(moves) != 1 || moves[0] != [2]int{38, 36} {
+		t.Fatalf("first poll moves %v, want e7-e5", moves)
+	}
+
+	//between two polls black takes e5 back and plays d5, which gets the same MIndex
+	takeBack := func() {
+		t.Helper()
+		if err := blackClient.MakeOffer(ctx, game.GameID, api.TakebackOffer); err != nil {
+			t.Fatal(err)
+		}
+		offers, err := whiteClient.Offers(ctx, game.GameID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := whiteClient.RespondToOffer(ctx, game.GameID, offers[len(offers)-1].OfferID, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	takeBack()
+	play(t, whiteClient, blackClient, game.GameID, true, "d7d5")
+
+	events = nil
+	if _, err := poll(collect(&events)); err != nil {
+		t.Fatal(err)
+	}
+	if moves := movesIn(events); len(moves) != 1 || moves[0] != [2]int{30, 28} {
+		t.Errorf("moves after the takeback %v, want d7-d5", moves)
+	}
+
+	//even the very move that was taken back is sent when it is played again
+	takeBack()
+	play(t, whiteClient, blackClient, game.GameID, true, "d7d5")
+	events = nil
+	if _, err := poll(collect(&events)); err != nil {
+		t.Fatal(err)
+	}
+	if moves := movesIn(events); len(moves) != 1 || moves[0] != [2]int{30, 28} {
+		t.Errorf("moves after playing the same move again %v, want d7-d5", moves)
+	}
+
+	//nothing new is nothing sent
+	events = nil
+	active, err := poll(collect(&events))
+	if err != nil || active || len(events) != 0 {
+		t.Errorf("quiet poll: active %v, events %+v, error %v", active, events, err)
+	}
+}
+
+// requestLog sends requests on through next, noting each one's path and query.
+type requestLog struct {
+	next http.RoundTripper
+
+	mu   sync.Mutex
+	seen []string
+}
+
+func (l *requestLog) RoundTrip(req *http.Request) (*http.Response, error) {
+	l.mu.Lock()
+	l.seen = append(l.seen, req.URL.RequestURI())
+	l.mu.Unlock()
+	return l.next.RoundTrip(req)
+}
+
+// saw reports whether any request so far had part in its path or query.
+func (l *requestLog) saw(part string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, uri := range l.seen {
+		if strings.Contains(uri, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// logRequests puts a requestLog in front of client's transport.
+func logRequests(client *api.Client) *requestLog {
+	log := &requestLog{next: client.HTTP.Transport}
+	client.HTTP.Transport = log
+	return log
+}
+
+/*
+nextEvent reads events until one that want matches, failing if none comes
+within a few seconds or watching stops first.
+*/
+func nextEvent(t *testing.T, events <-chan api.GameEvent, what string, want func(api.GameEvent) bool) api.GameEvent {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatalf("watching stopped waiting for %s", what)
+			}
+			if want(ev) {
+				return ev
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+// moveEvent matches the move from and to, numbered file*8+rank.
+func moveEvent(from int, to int) func(api.GameEvent) bool {
+	return func(ev api.GameEvent) bool {
+		return ev.Move != nil && ev.Move.MFrom == from && ev.Move.MTo == to
+	}
+}
+
+// watchToEnd resigns as resigner and checks watching ends with ErrGameOver.
+func watchToEnd(t *testing.T, events <-chan api.GameEvent, resigner *api.Client, gameID int) {
+	t.Helper()
+	if err := resigner.Resign(context.Background(), gameID); err != nil {
+		t.Fatal(err)
+	}
+	ev := nextEvent(t, events, "the game to end", func(ev api.GameEvent) bool {
+		return ev.Err != nil
+	})
+	if !errors.Is(ev.Err, api.ErrGameOver) {
+		t.Errorf("watch ended with %v, want ErrGameOver", ev.Err)
+	}
+	select {
+	case ev, ok := <-events:
+		if ok {
+			t.Errorf("event after the game ended: %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("events not closed after the game ended")
+	}
+}
+
+func TestWatchGameStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, whiteClient, blackClient, game := watchedGame(t)
+	log := logRequests(whiteClient)
+
+	events := whiteClient.WatchGame(ctx, game.GameID)
+	play(t, whiteClient, blackClient, game.GameID, false, "e2e4", "e7e5")
+	nextEvent(t, events, "e2-e4", moveEvent(33, 35))
+	nextEvent(t, events, "e7-e5", moveEvent(38, 36))
+
+	if _, err := blackClient.SendChat(ctx, game.GameID, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	nextEvent(t, events, "black's chat", func(ev api.GameEvent) bool {
+		return ev.Chat != nil && ev.Chat.Text == "hello"
+	})
+	if err := blackClient.MakeOffer(ctx, game.GameID, api.DrawOffer); err != nil {
+		t.Fatal(err)
+	}
+	nextEvent(t, events, "black's draw offer", func(ev api.GameEvent) bool {
+		return ev.Offer != nil && ev.Offer.Kind == api.DrawOffer && ev.Offer.State == api.OfferPending
+	})
+
+	//after the connection drops the client reconnects and says so, then hears about later moves
+	s.DropEventStreams()
+	nextEvent(t, events, "the stream to reconnect", func(ev api.GameEvent) bool {
+		return ev.Reconnected
+	})
+	play(t, whiteClient, blackClient, game.GameID, false, "d2d4")
+	nextEvent(t, events, "d2-d4 after reconnecting", moveEvent(25, 27))
+
+	watchToEnd(t, events, blackClient, game.GameID)
+	if !log.saw("/events") || log.saw("last_move") {
+		t.Errorf("watched with %v, want only the event stream", log.seen)
+	}
+}
+
+func TestWatchGameLongPoll(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, whiteClient, blackClient, game := watchedGame(t)
+	s.NoEventStream = true
+	log := logRequests(whiteClient)
+
+	events := whiteClient.WatchGame(ctx, game.GameID)
+	play(t, whiteClient, blackClient, game.GameID, false, "e2e4")
+	nextEvent(t, events, "e2-e4", moveEvent(33, 35))
+
+	//a held poll hears about a move as soon as it is made, sooner than the next plain poll would
+	time.Sleep(200 * time.Millisecond)
+	played := time.Now()
+	play(t, whiteClient, blackClient, game.GameID, true, "e7e5")
+	nextEvent(t, events, "e7-e5", moveEvent(38, 36))
+	if took := time.Since(played); took > 500*time.Millisecond {
+		t.Errorf("move took %v to arrive while long polling", took)
+	}
+
+	if _, err := blackClient.SendChat(ctx, game.GameID, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	nextEvent(t, events, "black's chat", func(ev api.GameEvent) bool {
+		return ev.Chat != nil && ev.Chat.Text == "hello"
+	})
+
+	watchToEnd(t, events, blackClient, game.GameID)
+	if !log.saw("/events") || !log.saw("wait=") {
+		t.Errorf("watched with %v, want the event stream tried then long polls", log.seen)
+	}
+}
+
+func TestWatchGamePoll(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, whiteClient, blackClient, game := watchedGame(t)
+	s.NoEventStream = true
+	s.NoLongPoll = true
+	log := logRequests(whiteClient)
+
+	events := whiteClient.WatchGame(ctx, game.GameID)
+	play(t, whiteClient, blackClient, game.GameID, false, "e2e4", "e7e5")
+	nextEvent(t, events, "e7-e5", moveEvent(38, 36))
+	play(t, whiteClient, blackClient, game.GameID, false, "d2d4")
+	nextEvent(t, events, "d2-d4", moveEvent(25, 27))
+
+	watchToEnd(t, events, blackClient, game.GameID)
+	if log.saw("wait=") {
+		t.Errorf("asked a server that doesn't long poll to wait: %v", log.seen)
+	}
+}
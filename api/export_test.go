@@ -0,0 +1,14 @@
+package api
+
+import "context"
+
+// PollOnce polls a game once the way WatchGame does without an event stream, for the tests in api_test.
+type PollOnce = func(send func(GameEvent) bool) (active bool, err error)
+
+// NewPoller starts polling gameID, remembering what has been sent between calls of the returned func.
+func NewPoller(c *Client, gameID int) PollOnce {
+	poller := newGamePoller(c, gameID)
+	return func(send func(GameEvent) bool) (bool, error) {
+		return poller.poll(context.Background(), send)
+	}
+}
@@ -0,0 +1,150 @@
+package fakeserver
+
+import "errors"
+
+type piece struct {
+	kind  string //empty for no piece
+	black bool
+}
+
+// square is a board position, file and rank both 0-7.
+type square struct {
+	file int
+	rank int
+}
+
+// board is indexed [rank][file], the same way the client lays out its tiles.
+type board [8][8]piece
+
+// index is the square as the server numbers it in move lists.
+func (sq square) index() int {
+	return sq.file*8 + sq.rank
+}
+
+// squareAt is the square numbered index in move lists.
+func squareAt(index int) square {
+	return square{file: index / 8, rank: index % 8}
+}
+
+func (sq square) onBoard() bool {
+	return sq.file >= 0 && sq.file < 8 && sq.rank >= 0 && sq.rank < 8
+}
+
+func newBoard() board {
+	var b board
+	backRank := [8]string{"rook", "knight", "bishop", "queen", "king", "bishop", "knight", "rook"}
+	for file := 0; file < 8; file++ {
+		b[0][file] = piece{kind: backRank[file]}
+		b[1][file] = piece{kind: "pawn"}
+		b[6][file] = piece{kind: "pawn", black: true}
+		b[7][file] = piece{kind: backRank[file], black: true}
+	}
+	return b
+}
+
+func (b *board) at(sq square) piece {
+	return b[sq.rank][sq.file]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
+
+// pathClear checks every square strictly between from and to is empty. They must share a line or diagonal.
+func (b *board) pathClear(from square, to square) bool {
+	stepFile, stepRank := sign(to.file-from.file), sign(to.rank-from.rank)
+	for sq := (square{from.file + stepFile, from.rank + stepRank}); sq != to; sq = (square{sq.file + stepFile, sq.rank + stepRank}) {
+		if b.at(sq).kind != "" {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+checkMove returns an error if the side to move (black or not) cannot move the piece on from to to.
+It follows the same movement rules as the client: there is no check, castling or en passant.
+*/
+func (b *board) checkMove(from square, to square, black bool) error {
+	if !from.onBoard() || !to.onBoard() {
+		return errors.New("square is off the board")
+	}
+	if from == to {
+		return errors.New("piece must move")
+	}
+
+	moving := b.at(from)
+	if moving.kind == "" {
+		return errors.New("no piece on that square")
+	}
+	if moving.black != black {
+		return errors.New("that piece is not yours")
+	}
+	target := b.at(to)
+	if target.kind != "" && target.black == black {
+		return errors.New("cannot take your own piece")
+	}
+
+	fileDiff, rankDiff := to.file-from.file, to.rank-from.rank
+	straight := fileDiff == 0 || rankDiff == 0
+	diagonal := abs(fileDiff) == abs(rankDiff)
+
+	legal := false
+	switch moving.kind {
+	case "pawn":
+		forward, startRank := 1, 1
+		if black {
+			forward, startRank = -1, 6
+		}
+		switch {
+		case fileDiff == 0 && rankDiff == forward:
+			legal = target.kind == ""
+		case fileDiff == 0 && rankDiff == 2*forward && from.rank == startRank:
+			legal = target.kind == "" && b.pathClear(from, to)
+		case abs(fileDiff) == 1 && rankDiff == forward:
+			legal = target.kind != ""
+		}
+	case "knight":
+		legal = abs(fileDiff)*abs(rankDiff) == 2
+	case "bishop":
+		legal = diagonal && b.pathClear(from, to)
+	case "rook":
+		legal = straight && b.pathClear(from, to)
+	case "queen":
+		legal = (straight || diagonal) && b.pathClear(from, to)
+	case "king":
+		legal = abs(fileDiff) <= 1 && abs(rankDiff) <= 1
+	}
+
+	if !legal {
+		return errors.New("illegal move for a " + moving.kind)
+	}
+	return nil
+}
+
+// apply makes a move already passed by checkMove, returning whatever was taken.
+func (b *board) apply(from square, to square) piece {
+	moving, captured := b.at(from), b.at(to)
+
+	//like the client, only pawns reaching rank 7 become queens, so black pawns never promote
+	if moving.kind == "pawn" && to.rank == 7 {
+		moving.kind = "queen"
+	}
+
+	b[to.rank][to.file] = moving
+	b[from.rank][from.file] = piece{}
+	return captured
+}
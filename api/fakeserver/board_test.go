@@ -0,0 +1,37 @@
+package fakeserver
+
+import "testing"
+
+// play checks and applies moves given as from and to squares, alternating from white.
+func play(t *testing.T, b *board, moves ...[2]square) {
+	t.Helper()
+	for i, move := range moves {
+		if err := b.checkMove(move[0], move[1], i%2 == 1); err != nil {
+			t.Fatalf("move %d %v: %v", i+1, move, err)
+		}
+		b.apply(move[0], move[1])
+	}
+}
+
+func TestPromotionMatchesClient(t *testing.T) {
+	b := newBoard()
+	//white's h pawn runs up and takes on g7 then h8, black's a pawn takes on b2 then a1
+	play(t, &b,
+		[2]square{{7, 1}, {7, 3}}, [2]square{{0, 6}, {0, 4}},
+		[2]square{{7, 3}, {7, 4}}, [2]square{{0, 4}, {0, 3}},
+		[2]square{{7, 4}, {7, 5}}, [2]square{{0, 3}, {0, 2}},
+		[2]square{{7, 5}, {6, 6}}, [2]square{{0, 2}, {1, 1}},
+		[2]square{{6, 6}, {7, 7}}, [2]square{{1, 1}, {0, 0}},
+	)
+
+	if got := b.at(square{7, 7}); got.kind != "queen" || got.black {
+		t.Errorf("white pawn on h8 is %+v, want a white queen", got)
+	}
+	//the client keeps a black pawn on the first rank a pawn, so the server must too
+	if got := b.at(square{0, 0}); got.kind != "pawn" || !got.black {
+		t.Errorf("black pawn on a1 is %+v, want a black pawn", got)
+	}
+	if err := b.checkMove(square{0, 0}, square{1, 0}, true); err == nil {
+		t.Error("a black pawn on the first rank moved sideways")
+	}
+}
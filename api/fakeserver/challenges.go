@@ -0,0 +1,130 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/jjj333-p/chess-fe-go/api"
+)
+
+// expireChallenges marks pending challenges past their expiry as expired.
+func (s *Server) expireChallenges() {
+	now := time.Now()
+	for _, ch := range s.challenges {
+		if ch.State == api.ChallengePending && ch.Expired(now) {
+			ch.State = api.ChallengeExpired
+		}
+	}
+}
+
+func (s *Server) handleNewChallenge(w http.ResponseWriter, r *http.Request, u *user) {
+	var req api.ChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	opponent, ok := s.users[req.ToID]
+	switch {
+	case !ok:
+		writeError(w, http.StatusBadRequest, "no such user")
+		return
+	case opponent.UID == u.UID:
+		writeError(w, http.StatusBadRequest, "cannot challenge yourself")
+		return
+	case req.Colour != api.ColourWhite && req.Colour != api.ColourBlack && req.Colour != api.ColourRandom:
+		writeError(w, http.StatusBadRequest, "colour must be W, B or random")
+		return
+	case req.TimeControl.Base < 0 || req.TimeControl.Increment < 0 ||
+		req.TimeControl.Delay < 0 || req.TimeControl.Delay > req.TimeControl.Base:
+		writeError(w, http.StatusBadRequest, "invalid time control")
+		return
+	}
+	if req.RematchOf != 0 {
+		g, ok := s.games[req.RematchOf]
+		players := ok && ((g.WhiteID == u.UID && g.BlackID == opponent.UID) || (g.BlackID == u.UID && g.WhiteID == opponent.UID))
+		if !players || g.Status == "" {
+			writeError(w, http.StatusBadRequest, "not a finished game between you to rematch")
+			return
+		}
+	}
+
+	ch := &api.Challenge{
+		ChallengeID: len(s.challenges) + 1,
+		FromID:      u.UID,
+		FromName:    u.Username,
+		ToID:        opponent.UID,
+		ToName:      opponent.Username,
+		Colour:      req.Colour,
+		TimeControl: req.TimeControl,
+		Rated:       req.Rated,
+		State:       api.ChallengePending,
+		ExpiresAt:   time.Now().Add(s.ChallengeTTL).UTC().Format(time.RFC3339),
+		RematchOf:   req.RematchOf,
+	}
+	s.challenges = append(s.challenges, ch)
+	writeJSON(w, ch)
+}
+
+func (s *Server) handleChallenges(w http.ResponseWriter, r *http.Request, u *user) {
+	s.expireChallenges()
+
+	challenges := make([]api.Challenge, 0)
+	for _, ch := range s.challenges {
+		if ch.FromID == u.UID || ch.ToID == u.UID {
+			challenges = append(challenges, *ch)
+		}
+	}
+	writeJSON(w, challenges)
+}
+
+func (s *Server) handleChallengeResponse(w http.ResponseWriter, r *http.Request, u *user) {
+	s.expireChallenges()
+
+	challengeID, err := pathInt(r, "cid")
+	if err != nil || challengeID < 1 || challengeID > len(s.challenges) {
+		writeError(w, http.StatusNotFound, "no such challenge")
+		return
+	}
+	ch := s.challenges[challengeID-1]
+
+	response := r.PathValue("response")
+	//the challenger can only cancel, and only the challenged player can answer
+	if (response == "cancel") != (ch.FromID == u.UID) || (ch.FromID != u.UID && ch.ToID != u.UID) {
+		writeError(w, http.StatusBadRequest, "you cannot "+response+" this challenge")
+		return
+	}
+	if ch.State != api.ChallengePending {
+		writeError(w, http.StatusBadRequest, "the challenge has already "+ch.State)
+		return
+	}
+
+	switch response {
+	case "accept":
+		challenger, challenged := s.users[ch.FromID], s.users[ch.ToID]
+		colour := ch.Colour
+		if colour == api.ColourRandom {
+			colour = []string{api.ColourWhite, api.ColourBlack}[rand.IntN(2)]
+		}
+		white, black := challenger, challenged
+		if colour == api.ColourBlack {
+			white, black = black, white
+		}
+
+		g := s.createGame(white, black, ch.TimeControl, ch.Rated)
+		ch.State = api.ChallengeAccepted
+		ch.GameID = g.GameID
+		writeJSON(w, g.snapshot())
+		return
+	case "decline":
+		ch.State = api.ChallengeDeclined
+	case "cancel":
+		ch.State = api.ChallengeCancelled
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	writeJSON(w, ch)
+}
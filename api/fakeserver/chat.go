@@ -0,0 +1,93 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jjj333-p/chess-fe-go/api"
+)
+
+// A user may send chatBurst messages in any chatWindow, across all their games.
+const (
+	chatBurst  = 5
+	chatWindow = 10 * time.Second
+)
+
+// handleChatHistory lets anyone read a game's chat, but only the players can send to it.
+func (s *Server) handleChatHistory(w http.ResponseWriter, r *http.Request, u *user) {
+	g := s.watchedGameFor(w, r)
+	if g == nil {
+		return
+	}
+
+	afterID := 0
+	if after := r.URL.Query().Get("after"); after != "" {
+		var err error
+		if afterID, err = strconv.Atoi(after); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid after")
+			return
+		}
+	}
+
+	messages := []api.ChatMessage{}
+	for _, message := range g.chat {
+		if message.MessageID > afterID {
+			messages = append(messages, message)
+		}
+	}
+	writeJSON(w, messages)
+}
+
+// handleSendChat stores a chat message. Players can keep chatting after the game is over.
+func (s *Server) handleSendChat(w http.ResponseWriter, r *http.Request, u *user) {
+	g := s.gameFor(w, r, u)
+	if g == nil {
+		return
+	}
+
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	text := strings.TrimSpace(body.Text)
+	if text == "" {
+		writeError(w, http.StatusBadRequest, "message is empty")
+		return
+	}
+	if len(text) > api.MaxChatLength {
+		writeError(w, http.StatusBadRequest, "message is too long")
+		return
+	}
+
+	now := time.Now()
+	recent := u.chatSent[:0]
+	for _, sent := range u.chatSent {
+		if now.Sub(sent) < chatWindow {
+			recent = append(recent, sent)
+		}
+	}
+	u.chatSent = recent
+	if len(u.chatSent) >= chatBurst {
+		writeError(w, http.StatusTooManyRequests, "you are sending messages too quickly")
+		return
+	}
+	u.chatSent = append(u.chatSent, now)
+
+	message := api.ChatMessage{
+		MessageID: len(g.chat) + 1,
+		GameID:    g.GameID,
+		UID:       u.UID,
+		Username:  u.Username,
+		Text:      text,
+		SentAt:    now.UTC().Format(time.RFC3339),
+	}
+	g.chat = append(g.chat, message)
+	g.touch()
+	writeJSON(w, message)
+}
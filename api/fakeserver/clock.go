@@ -0,0 +1,88 @@
+package fakeserver
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jjj333-p/chess-fe-go/api"
+)
+
+// colourIndex is 0 for white and 1 for black, to index a game's clocks.
+func colourIndex(colour string) int {
+	if colour == "B" {
+		return 1
+	}
+	return 0
+}
+
+// timeLeft is how long each player has at now. Only the player to move's clock runs, once it has started.
+func (g *game) timeLeft(now time.Time) [2]time.Duration {
+	left := g.clocks
+	if !g.turnStarted.IsZero() && g.Status == "" {
+		left[colourIndex(g.Turn)] -= now.Sub(g.turnStarted)
+	}
+	return left
+}
+
+/*
+pressClock stops the clock of the player who just moved, giving back time as
+the time control says, and starts their opponent's. Call it before g.Turn changes.
+The clocks start running after white's first move.
+*/
+func (g *game) pressClock(now time.Time) {
+	if g.TimeControl.Base == 0 {
+		return
+	}
+	if !g.turnStarted.IsZero() {
+		used := now.Sub(g.turnStarted)
+		g.clocks[colourIndex(g.Turn)] += g.TimeControl.Credit(used) - used
+	}
+	g.turnStarted = now
+}
+
+/*
+checkFlag ends the game if the player to move has run out of time. Nothing
+watches the clocks, so it is checked whenever the game is looked at.
+*/
+func (s *Server) checkFlag(g *game, now time.Time) {
+	if g.TimeControl.Base == 0 || g.Status != "" {
+		return
+	}
+	left := g.timeLeft(now)
+	if left[colourIndex(g.Turn)] > 0 {
+		return
+	}
+
+	g.clocks = left
+	g.clocks[colourIndex(g.Turn)] = 0
+	g.turnStarted = time.Time{}
+	if g.Turn == "B" {
+		s.finishGame(g, api.StatusWhiteWon, api.EndTimeout)
+	} else {
+		s.finishGame(g, api.StatusBlackWon, api.EndTimeout)
+	}
+}
+
+func (s *Server) handleClock(w http.ResponseWriter, r *http.Request, u *user) {
+	g := s.watchedGameFor(w, r)
+	if g == nil {
+		return
+	}
+	if g.TimeControl.Base == 0 {
+		writeError(w, http.StatusNotFound, "the game is untimed")
+		return
+	}
+
+	now := time.Now()
+	left := g.timeLeft(now)
+	running := ""
+	if !g.turnStarted.IsZero() && g.Status == "" {
+		running = g.Turn
+	}
+	writeJSON(w, api.ClockState{
+		WhiteMs:    max(left[0], 0).Milliseconds(),
+		BlackMs:    max(left[1], 0).Milliseconds(),
+		Running:    running,
+		ServerTime: now.UTC().Format(time.RFC3339Nano),
+	})
+}
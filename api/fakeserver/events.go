@@ -0,0 +1,147 @@
+package fakeserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jjj333-p/chess-fe-go/api"
+)
+
+// maxLongPoll caps how long a last move poll is held, whatever the client asks for.
+const maxLongPoll = 60 * time.Second
+
+// touch marks g as changed, waking anything waiting on it.
+func (g *game) touch() {
+	g.version++
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+/*
+waitForChange releases the lock until g changes, ctx is done or the server
+hangs up, and reports whether g changed. The player to move running out of
+time counts as a change, since nothing else would notice it.
+*/
+func (s *Server) waitForChange(ctx context.Context, g *game) bool {
+	version := g.version
+	if !g.turnStarted.IsZero() && g.Status == "" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeLeft(time.Now())[colourIndex(g.Turn)])
+		defer cancel()
+	}
+
+	changed, hangUp := g.changed, s.hangUp
+	s.mu.Unlock()
+	select {
+	case <-changed:
+	case <-ctx.Done():
+	case <-hangUp:
+	}
+	s.mu.Lock()
+
+	s.checkFlag(g, time.Now())
+	return g.version != version
+}
+
+/*
+DropEventStreams ends every event stream and long poll open now, as if the
+connections had dropped. Clients are free to connect again straight away.
+*/
+func (s *Server) DropEventStreams() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.hangUp)
+	s.hangUp = make(chan struct{})
+}
+
+// Close drops open event streams, which would otherwise keep the server from shutting down, then shuts it down.
+func (s *Server) Close() {
+	s.DropEventStreams()
+	s.Server.Close()
+}
+
+/*
+longPoll holds a last move request asking for changes since the game's
+current version until the game changes or the wait it asked for is up.
+*/
+func (s *Server) longPoll(r *http.Request, g *game) {
+	since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+	wait, _ := strconv.Atoi(r.URL.Query().Get("wait"))
+	if since != g.version || wait <= 0 || g.Status != "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), min(time.Duration(wait)*time.Second, maxLongPoll))
+	defer cancel()
+	s.waitForChange(ctx, g)
+}
+
+/*
+handleEvents streams a game's moves, offers and chat as server sent events,
+starting with everything so far, until the game ends or the client goes away.
+*/
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request, u *user) {
+	if s.NoEventStream {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	g := s.watchedGameFor(w, r)
+	if g == nil {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var sentMoves []api.DbMove
+	sentOffers := make(map[int]string)
+	sentChat := 0
+	for {
+		for _, offer := range g.offers {
+			if sentOffers[offer.OfferID] != offer.State {
+				writeEvent(w, "offer", offer)
+				sentOffers[offer.OfferID] = offer.State
+			}
+		}
+		for _, message := range g.chat[sentChat:] {
+			writeEvent(w, "chat", message)
+		}
+		sentChat = len(g.chat)
+
+		//moves taken back are gone, so whatever follows the moves still in the game is new
+		kept := 0
+		for kept < len(sentMoves) && kept < len(g.Moves) && sentMoves[kept] == g.Moves[kept] {
+			kept++
+		}
+		for _, move := range g.Moves[kept:] {
+			writeEvent(w, "move", move)
+		}
+		sentMoves = append([]api.DbMove{}, g.Moves...)
+
+		if g.Status != "" {
+			writeEvent(w, "game_over", g.snapshot())
+		}
+		flusher.Flush()
+
+		if g.Status != "" || !s.waitForChange(r.Context(), g) {
+			return
+		}
+	}
+}
+
+// writeEvent writes one server sent event with v as its json data.
+func writeEvent(w http.ResponseWriter, event string, v any) {
+	data, _ := json.Marshal(v)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
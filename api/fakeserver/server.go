@@ -0,0 +1,773 @@
+/*
+Package fakeserver is an in-memory stand-in for the chess server, for testing
+the client end to end without the real backend. It serves the same endpoints
+from an httptest.Server, keeps users, games and tournaments in memory, and
+checks that moves follow the piece movement rules before accepting them.
+
+Games can be watched on the /_game/{id}/events stream, or by long polling the
+last move. NoEventStream and NoLongPoll turn those off, to stand in for servers
+that only answer plain polls.
+*/
+package fakeserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jjj333-p/chess-fe-go/api"
+)
+
+type user struct {
+	api.DbUser
+	password string
+	chatSent []time.Time //when recent chat messages were sent, for rate limiting
+}
+
+type game struct {
+	api.DbGame
+	board       board
+	offers      []api.Offer
+	chat        []api.ChatMessage
+	clocks      [2]time.Duration //white's and black's time left when the current turn started
+	turnStarted time.Time        //zero until the clocks start
+	//clocks as they were when the turn of each move started, to put back on a takeback
+	clockHistory [][2]time.Duration
+	//version counts changes to the game, and changed is closed and replaced on each one
+	version int
+	changed chan struct{}
+}
+
+// Server is a running fake chess server. Point an api.Client at its URL.
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	users         map[int]*user
+	tokens        map[string]int //token to uid, 0 until the token is logged in
+	games         map[int]*game
+	tournaments   []api.DbTournament
+	registrations map[int]map[int]bool //tid to set of uids
+	nextUID       int
+	nextGameID    int
+	challenges    []*api.Challenge
+	hangUp        chan struct{} //closed to end the event streams and long polls open now
+
+	// ChallengeTTL is how long a challenge can go unanswered before it expires.
+	ChallengeTTL time.Duration
+	// NoEventStream answers the event stream with 404, like a server without one.
+	NoEventStream bool
+	// NoLongPoll answers last move polls straight away and without a game version.
+	NoLongPoll bool
+}
+
+// New starts a fake server with no users. Call Close when done with it.
+func New() *Server {
+	s := &Server{
+		users:         make(map[int]*user),
+		tokens:        make(map[string]int),
+		games:         make(map[int]*game),
+		registrations: make(map[int]map[int]bool),
+		nextUID:       1,
+		nextGameID:    1,
+		ChallengeTTL:  10 * time.Minute,
+		hangUp:        make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /request_token", s.handleRequestToken)
+	mux.HandleFunc("POST /_login/authenticate", s.handleAuthenticate)
+	mux.HandleFunc("POST /_login/create", s.handleCreate)
+	mux.HandleFunc("GET /_game/current", s.authed(s.handleCurrentGames))
+	mux.HandleFunc("GET /_game/old", s.authed(s.handleOldGames))
+	mux.HandleFunc("GET /_game/live", s.authed(s.handleLiveGames))
+	mux.HandleFunc("GET /_game/new/{uid}", s.authed(s.handleNewGame))
+	mux.HandleFunc("POST /_game/{id}/move", s.authed(s.handleMove))
+	mux.HandleFunc("POST /_game/{id}/resign", s.authed(s.handleResign))
+	mux.HandleFunc("POST /_game/{id}/offer", s.authed(s.handleOffer))
+	mux.HandleFunc("POST /_game/{id}/offer/{oid}/{response}", s.authed(s.handleOfferResponse))
+	mux.HandleFunc("POST /_game/{id}/chat", s.authed(s.handleSendChat))
+	mux.HandleFunc("GET /_game/{id}/{action}", s.authed(s.handleGameAction))
+	mux.HandleFunc("POST /_challenge/new", s.authed(s.handleNewChallenge))
+	mux.HandleFunc("GET /_challenge/list", s.authed(s.handleChallenges))
+	mux.HandleFunc("POST /_challenge/{cid}/{response}", s.authed(s.handleChallengeResponse))
+	mux.HandleFunc("GET /_user/list", s.authed(s.handleUserList))
+	mux.HandleFunc("GET /_user/{uid}", s.authed(s.handleUser))
+	mux.HandleFunc("GET /_leaderboard/current", s.authed(s.handleLeaderboard))
+	mux.HandleFunc("GET /_tournament/list", s.authed(s.handleTournaments))
+	mux.HandleFunc("GET /_tournament/register/{tid}", s.authed(s.handleTournamentRegister))
+
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// AddUser creates an account, as if it had registered.
+func (s *Server) AddUser(username string, password string) api.DbUser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addUser(username, password).DbUser
+}
+
+// AddTournament makes a tournament available to list and register for.
+func (s *Server) AddTournament(tournament api.DbTournament) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tournaments = append(s.tournaments, tournament)
+}
+
+// Game returns a copy of a game's current state, and whether it exists.
+func (s *Server) Game(gameID int) (api.DbGame, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.games[gameID]
+	if !ok {
+		return api.DbGame{}, false
+	}
+	return g.snapshot(), true
+}
+
+// ExpireSessions forgets every token, so the next authenticated request gets a 401.
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]int)
+}
+
+func (s *Server) addUser(username string, password string) *user {
+	u := &user{
+		DbUser: api.DbUser{
+			UID:        s.nextUID,
+			Username:   username,
+			CurrentElo: 1000,
+			PeakElo:    1000,
+		},
+		password: password,
+	}
+	s.users[u.UID] = u
+	s.nextUID++
+	return u
+}
+
+func (s *Server) userByName(username string) *user {
+	for _, u := range s.users {
+		if u.Username == username {
+			return u
+		}
+	}
+	return nil
+}
+
+func (g *game) snapshot() api.DbGame {
+	copied := g.DbGame
+	copied.Moves = append([]api.DbMove(nil), g.Moves...)
+	return copied
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// pathInt reads an integer wildcard from the request path.
+func pathInt(r *http.Request, name string) (int, error) {
+	n, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		return 0, errors.New("invalid " + name)
+	}
+	return n, nil
+}
+
+// authed wraps a handler that needs a logged in token, passing it the user. The lock is held while it runs.
+func (s *Server) authed(handler func(w http.ResponseWriter, r *http.Request, u *user)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		uid := s.tokens[r.Header.Get("token")]
+		u, ok := s.users[uid]
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		handler(w, r, u)
+	}
+}
+
+func (s *Server) handleRequestToken(w http.ResponseWriter, r *http.Request) {
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	token := hex.EncodeToString(raw)
+
+	s.mu.Lock()
+	s.tokens[token] = 0
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]string{"token": token})
+}
+
+// readLogin decodes credentials and checks the token they are for was handed out.
+func (s *Server) readLogin(w http.ResponseWriter, r *http.Request) (api.Credentials, string, bool) {
+	var creds api.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return creds, "", false
+	}
+	token := r.Header.Get("token")
+	if _, ok := s.tokens[token]; !ok {
+		writeError(w, http.StatusUnauthorized, "unknown token")
+		return creds, "", false
+	}
+	return creds, token, true
+}
+
+func (s *Server) handleAuthenticate(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	creds, token, ok := s.readLogin(w, r)
+	if !ok {
+		return
+	}
+
+	u := s.userByName(creds.Username)
+	if u == nil || u.password != creds.Password {
+		writeError(w, http.StatusUnauthorized, "invalid username or password")
+		return
+	}
+
+	s.tokens[token] = u.UID
+	writeJSON(w, map[string]string{"token": token})
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	creds, token, ok := s.readLogin(w, r)
+	if !ok {
+		return
+	}
+
+	if creds.Username == "" || creds.Password == "" {
+		writeError(w, http.StatusBadRequest, "username and password are required")
+		return
+	}
+	if s.userByName(creds.Username) != nil {
+		writeError(w, http.StatusBadRequest, "username is already taken")
+		return
+	}
+
+	u := s.addUser(creds.Username, creds.Password)
+	s.tokens[token] = u.UID
+	writeJSON(w, map[string]string{"token": token})
+}
+
+// gamesFor lists a user's games that are finished or not, oldest first. A nil user lists everyone's.
+func (s *Server) gamesFor(u *user, finished bool) []api.DbGame {
+	games := make([]api.DbGame, 0)
+	for _, g := range s.games {
+		if u != nil && g.WhiteID != u.UID && g.BlackID != u.UID {
+			continue
+		}
+		if (g.Status != "") == finished {
+			games = append(games, g.snapshot())
+		}
+	}
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].GameID < games[j].GameID
+	})
+	return games
+}
+
+func (s *Server) handleCurrentGames(w http.ResponseWriter, r *http.Request, u *user) {
+	writeJSON(w, s.gamesFor(u, false))
+}
+
+func (s *Server) handleOldGames(w http.ResponseWriter, r *http.Request, u *user) {
+	writeJSON(w, s.gamesFor(u, true))
+}
+
+func (s *Server) handleLiveGames(w http.ResponseWriter, r *http.Request, u *user) {
+	writeJSON(w, s.gamesFor(nil, false))
+}
+
+// handleNewGame starts a game with the requesting user as white.
+func (s *Server) handleNewGame(w http.ResponseWriter, r *http.Request, u *user) {
+	opponentUID, err := pathInt(r, "uid")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opponent, ok := s.users[opponentUID]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "no such user")
+		return
+	}
+	if opponent.UID == u.UID {
+		writeError(w, http.StatusBadRequest, "cannot play against yourself")
+		return
+	}
+
+	g := s.createGame(u, opponent, api.TimeControl{}, true)
+	writeJSON(w, g.snapshot())
+}
+
+func (s *Server) createGame(white *user, black *user, timeControl api.TimeControl, rated bool) *game {
+	g := &game{
+		DbGame: api.DbGame{
+			GameID:      s.nextGameID,
+			Date:        time.Now().Format("2006-01-02"),
+			WhiteID:     white.UID,
+			WhiteName:   white.Username,
+			WhiteElo:    white.CurrentElo,
+			BlackID:     black.UID,
+			BlackName:   black.Username,
+			BlackElo:    black.CurrentElo,
+			Turn:        "W",
+			Moves:       []api.DbMove{},
+			TimeControl: timeControl,
+			Rated:       rated,
+		},
+		board:   newBoard(),
+		version: 1,
+		changed: make(chan struct{}),
+	}
+	base := time.Duration(timeControl.Base) * time.Second
+	g.clocks = [2]time.Duration{base, base}
+	s.games[g.GameID] = g
+	s.nextGameID++
+	return g
+}
+
+// watchedGameFor finds a game by the id in the path, for anyone to look at.
+func (s *Server) watchedGameFor(w http.ResponseWriter, r *http.Request) *game {
+	gameID, err := pathInt(r, "id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return nil
+	}
+	g, ok := s.games[gameID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "no such game")
+		return nil
+	}
+	s.checkFlag(g, time.Now())
+	return g
+}
+
+// gameFor finds a game by the id in the path, checking the user is playing in it.
+func (s *Server) gameFor(w http.ResponseWriter, r *http.Request, u *user) *game {
+	g := s.watchedGameFor(w, r)
+	if g == nil {
+		return nil
+	}
+	if g.WhiteID != u.UID && g.BlackID != u.UID {
+		writeError(w, http.StatusUnauthorized, "not a player in this game")
+		return nil
+	}
+	return g
+}
+
+func (s *Server) handleMove(w http.ResponseWriter, r *http.Request, u *user) {
+	g := s.gameFor(w, r, u)
+	if g == nil {
+		return
+	}
+	if g.Status != "" {
+		writeError(w, http.StatusPreconditionFailed, "the game is over")
+		return
+	}
+
+	var moveReq api.MoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&moveReq); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	black := g.Turn == "B"
+	if (black && u.UID != g.BlackID) || (!black && u.UID != g.WhiteID) {
+		writeError(w, http.StatusBadRequest, "it is not your turn")
+		return
+	}
+
+	from := square{file: moveReq.MFrom[0], rank: moveReq.MFrom[1]}
+	to := square{file: moveReq.MTo[0], rank: moveReq.MTo[1]}
+	if err := g.board.checkMove(from, to, black); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	//moving declines whatever the opponent had offered, and any takeback as it was for an earlier move
+	for i := range g.offers {
+		offer := &g.offers[i]
+		if offer.State == api.OfferPending && (offer.By != g.Turn || offer.Kind == api.TakebackOffer) {
+			offer.State = api.OfferDeclined
+		}
+	}
+
+	g.clockHistory = append(g.clockHistory, g.clocks)
+	g.pressClock(time.Now())
+	captured := g.board.apply(from, to)
+	g.Moves = append(g.Moves, api.DbMove{
+		MIndex:    len(g.Moves) + 1,
+		GameID:    g.GameID,
+		MFrom:     from.index(),
+		MTo:       to.index(),
+		PieceName: moveReq.PieceID,
+	})
+
+	if black {
+		g.Turn = "W"
+	} else {
+		g.Turn = "B"
+	}
+
+	//there is no check in this game, taking the king wins
+	if captured.kind == "king" {
+		if black {
+			s.finishGame(g, api.StatusBlackWon, "")
+		} else {
+			s.finishGame(g, api.StatusWhiteWon, "")
+		}
+	}
+	g.touch()
+
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+// colourOf is "W" or "B" for a player in g.
+func colourOf(g *game, u *user) string {
+	if u.UID == g.BlackID {
+		return "B"
+	}
+	return "W"
+}
+
+/*
+takeBack undoes the last move made by the player with colour, and the reply
+to it if there was one, so it is their turn again. Both clocks go back to how
+they were when that turn started, and the clock restarts from now.
+*/
+func (g *game) takeBack(colour string, now time.Time) {
+	undo := 1
+	if g.Turn == colour {
+		undo = 2
+	}
+	g.Moves = g.Moves[:len(g.Moves)-undo]
+	g.Turn = colour
+
+	g.clocks = g.clockHistory[len(g.Moves)]
+	g.clockHistory = g.clockHistory[:len(g.Moves)]
+	//taking back white's first move leaves the clocks waiting for it again
+	if g.TimeControl.Base > 0 && len(g.Moves) > 0 {
+		g.turnStarted = now
+	} else {
+		g.turnStarted = time.Time{}
+	}
+
+	//replaying is simpler than undoing captures and promotions
+	g.board = newBoard()
+	for _, move := range g.Moves {
+		g.board.apply(squareAt(move.MFrom), squareAt(move.MTo))
+	}
+}
+
+// finishGame ends g with the given status, updating both players' stats.
+func (s *Server) finishGame(g *game, status string, reason string) {
+	g.Status = status
+	g.EndReason = reason
+	g.touch()
+	for i := range g.offers {
+		if g.offers[i].State == api.OfferPending {
+			g.offers[i].State = api.OfferDeclined
+		}
+	}
+
+	white, black := s.users[g.WhiteID], s.users[g.BlackID]
+	white.TotalGames++
+	black.TotalGames++
+
+	if status == api.StatusDraw {
+		for _, u := range []*user{white, black} {
+			u.GamesDraw++
+			u.WinStreak = 0
+			u.LoseStreak = 0
+		}
+		return
+	}
+
+	winner, loser := white, black
+	if status == api.StatusBlackWon {
+		winner, loser = loser, winner
+	}
+
+	winner.GamesWon++
+	winner.WinStreak++
+	winner.LoseStreak = 0
+
+	loser.GamesLost++
+	loser.LoseStreak++
+	loser.WinStreak = 0
+
+	if g.Rated {
+		winner.CurrentElo += 10
+		winner.PeakElo = max(winner.PeakElo, winner.CurrentElo)
+		loser.CurrentElo -= 10
+	}
+}
+
+// ongoingGameFor is gameFor, but also refuses games that have finished.
+func (s *Server) ongoingGameFor(w http.ResponseWriter, r *http.Request, u *user) *game {
+	g := s.gameFor(w, r, u)
+	if g != nil && g.Status != "" {
+		writeError(w, http.StatusPreconditionFailed, "the game is over")
+		return nil
+	}
+	return g
+}
+
+func (s *Server) handleResign(w http.ResponseWriter, r *http.Request, u *user) {
+	g := s.ongoingGameFor(w, r, u)
+	if g == nil {
+		return
+	}
+
+	if colourOf(g, u) == "B" {
+		s.finishGame(g, api.StatusWhiteWon, api.EndResignation)
+	} else {
+		s.finishGame(g, api.StatusBlackWon, api.EndResignation)
+	}
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleOffer(w http.ResponseWriter, r *http.Request, u *user) {
+	g := s.ongoingGameFor(w, r, u)
+	if g == nil {
+		return
+	}
+
+	var body struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	colour := colourOf(g, u)
+	switch body.Kind {
+	case api.DrawOffer:
+	case api.TakebackOffer:
+		//white makes the first move, so black needs two on the board to have one to take back
+		if len(g.Moves) == 0 || (colour == "B" && len(g.Moves) < 2) {
+			writeError(w, http.StatusBadRequest, "you have no move to take back")
+			return
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "unknown offer kind")
+		return
+	}
+	for _, offer := range g.offers {
+		if offer.State == api.OfferPending && offer.Kind == body.Kind {
+			writeError(w, http.StatusBadRequest, "there is already a "+body.Kind+" offer pending")
+			return
+		}
+	}
+
+	g.offers = append(g.offers, api.Offer{
+		OfferID: len(g.offers) + 1,
+		GameID:  g.GameID,
+		Kind:    body.Kind,
+		By:      colour,
+		State:   api.OfferPending,
+	})
+	g.touch()
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleOfferResponse(w http.ResponseWriter, r *http.Request, u *user) {
+	g := s.ongoingGameFor(w, r, u)
+	if g == nil {
+		return
+	}
+	offerID, err := pathInt(r, "oid")
+	if err != nil || offerID < 1 || offerID > len(g.offers) {
+		writeError(w, http.StatusNotFound, "no such offer")
+		return
+	}
+	offer := &g.offers[offerID-1]
+	if offer.By == colourOf(g, u) {
+		writeError(w, http.StatusBadRequest, "cannot answer your own offer")
+		return
+	}
+	if offer.State != api.OfferPending {
+		writeError(w, http.StatusBadRequest, "the offer has already been "+offer.State)
+		return
+	}
+
+	switch r.PathValue("response") {
+	case "accept":
+		offer.State = api.OfferAccepted
+		switch offer.Kind {
+		case api.DrawOffer:
+			s.finishGame(g, api.StatusDraw, api.EndAgreement)
+		case api.TakebackOffer:
+			g.takeBack(offer.By, time.Now())
+		}
+	case "decline":
+		offer.State = api.OfferDeclined
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	g.touch()
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+/*
+handleGameAction serves the GET endpoints under a game. They share one pattern
+because a plain /_game/{id}/last_move would clash with /_game/new/{uid}.
+*/
+func (s *Server) handleGameAction(w http.ResponseWriter, r *http.Request, u *user) {
+	switch r.PathValue("action") {
+	case "last_move":
+		s.handleLastMove(w, r, u)
+	case "offers":
+		if g := s.watchedGameFor(w, r); g != nil {
+			writeJSON(w, append([]api.Offer{}, g.offers...))
+		}
+	case "chat":
+		s.handleChatHistory(w, r, u)
+	case "clock":
+		s.handleClock(w, r, u)
+	case "events":
+		s.handleEvents(w, r, u)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) handleLastMove(w http.ResponseWriter, r *http.Request, u *user) {
+	g := s.watchedGameFor(w, r)
+	if g == nil {
+		return
+	}
+	if !s.NoLongPoll {
+		s.longPoll(r, g)
+		w.Header().Set(api.GameVersionHeader, strconv.Itoa(g.version))
+	}
+	if g.Status != "" {
+		writeError(w, http.StatusPreconditionFailed, "the game is over")
+		return
+	}
+
+	//the real server sends an empty move before the first one is made
+	if len(g.Moves) == 0 {
+		writeJSON(w, api.DbMove{})
+		return
+	}
+	writeJSON(w, g.Moves[len(g.Moves)-1])
+}
+
+func (s *Server) handleUserList(w http.ResponseWriter, r *http.Request, u *user) {
+	users := make([]api.DbUser, 0, len(s.users))
+	for _, other := range s.users {
+		users = append(users, other.DbUser)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].UID < users[j].UID
+	})
+	writeJSON(w, users)
+}
+
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request, u *user) {
+	uid, err := pathInt(r, "uid")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	other, ok := s.users[uid]
+	if !ok {
+		writeError(w, http.StatusNotFound, "no such user")
+		return
+	}
+	writeJSON(w, other.DbUser)
+}
+
+func (s *Server) handleLeaderboard(w http.ResponseWriter, r *http.Request, u *user) {
+	leaderboard := make([]api.LeaderboardEntry, 0, len(s.users))
+	for _, other := range s.users {
+		leaderboard = append(leaderboard, api.LeaderboardEntry{
+			UID:      other.UID,
+			Username: other.Username,
+			Rating:   float64(other.CurrentElo),
+		})
+	}
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].Rating != leaderboard[j].Rating {
+			return leaderboard[i].Rating > leaderboard[j].Rating
+		}
+		return leaderboard[i].UID < leaderboard[j].UID
+	})
+	for i := range leaderboard {
+		leaderboard[i].Rank = i + 1
+	}
+	writeJSON(w, leaderboard)
+}
+
+func (s *Server) handleTournaments(w http.ResponseWriter, r *http.Request, u *user) {
+	tournaments := make([]api.DbTournament, len(s.tournaments))
+	for i, t := range s.tournaments {
+		t.CanRegister = t.CanRegister && !s.registrations[t.TID][u.UID] &&
+			u.CurrentElo >= t.MinElo && (t.MaxElo == 0 || u.CurrentElo <= t.MaxElo)
+		tournaments[i] = t
+	}
+	writeJSON(w, tournaments)
+}
+
+func (s *Server) handleTournamentRegister(w http.ResponseWriter, r *http.Request, u *user) {
+	tid, err := pathInt(r, "tid")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var tournament *api.DbTournament
+	for i := range s.tournaments {
+		if s.tournaments[i].TID == tid {
+			tournament = &s.tournaments[i]
+		}
+	}
+	switch {
+	case tournament == nil:
+		writeError(w, http.StatusBadRequest, "no such tournament")
+		return
+	case !tournament.CanRegister:
+		writeError(w, http.StatusBadRequest, "registration is closed")
+		return
+	case s.registrations[tid][u.UID]:
+		writeError(w, http.StatusBadRequest, "already registered")
+		return
+	case u.CurrentElo < tournament.MinElo || (tournament.MaxElo != 0 && u.CurrentElo > tournament.MaxElo):
+		writeError(w, http.StatusBadRequest, "elo is outside the tournament range")
+		return
+	}
+
+	if s.registrations[tid] == nil {
+		s.registrations[tid] = make(map[int]bool)
+	}
+	s.registrations[tid][u.UID] = true
+	writeJSON(w, map[string]string{"status": "ok"})
+}
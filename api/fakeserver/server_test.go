@@ -0,0 +1,421 @@
+package fakeserver_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jjj333-p/chess-fe-go/api"
+	"github.com/jjj333-p/chess-fe-go/api/fakeserver"
+)
+
+var ctx = context.Background()
+
+// login logs a client in to s as username, whose password is "pw".
+func login(t *testing.T, s *fakeserver.Server, username string) *api.Client {
+	t.Helper()
+	client := api.NewClient(s.URL)
+	if err := client.Login(ctx, api.Credentials{Username: username, Password: "pw"}); err != nil {
+		t.Fatalf("logging in as %s: %v", username, err)
+	}
+	return client
+}
+
+/*
+newGame has white challenge black with timeControl and black accept, returning
+the clients for white and black and the new game.
+*/
+func newGame(t *testing.T, timeControl api.TimeControl) (*fakeserver.Server, *api.Client, *api.Client, *api.DbGame) {
+	t.Helper()
+	s := fakeserver.New()
+	t.Cleanup(s.Close)
+	s.AddUser("white", "pw")
+	black := s.AddUser("black", "pw")
+	whiteClient, blackClient := login(t, s, "white"), login(t, s, "black")
+
+	ch, err := whiteClient.SendChallenge(ctx, api.ChallengeRequest{ToID: black.UID, Colour: api.ColourWhite, TimeControl: timeControl})
+	if err != nil {
+		t.Fatal(err)
+	}
+	game, err := blackClient.AcceptChallenge(ctx, ch.ChallengeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, whiteClient, blackClient, game
+}
+
+// move plays a move given in algebraic squares, like "e2" to "e4".
+func move(client *api.Client, gameID int, pieceID string, from string, to string) error {
+	square := func(name string) [2]int {
+		return [2]int{int(name[0] - 'a'), int(name[1] - '1')}
+	}
+	return client.MakeMove(ctx, gameID, api.MoveRequest{PieceID: pieceID, MFrom: square(from), MTo: square(to)})
+}
+
+func TestLoginAndRegister(t *testing.T) {
+	s := fakeserver.New()
+	defer s.Close()
+	s.AddUser("alice", "pw")
+
+	client := api.NewClient(s.URL)
+	if err := client.Login(ctx, api.Credentials{Username: "alice", Password: "wrong"}); !errors.Is(err, api.ErrUnauthorized) {
+		t.Errorf("wrong password: got %v, want ErrUnauthorized", err)
+	}
+	if _, err := client.CurrentGames(ctx); !errors.Is(err, api.ErrUnauthorized) {
+		t.Errorf("before logging in: got %v, want ErrUnauthorized", err)
+	}
+	if err := client.Login(ctx, api.Credentials{Username: "alice", Password: "pw"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.VerifySession(ctx); err != nil {
+		t.Errorf("session after logging in: %v", err)
+	}
+
+	newcomer := api.NewClient(s.URL)
+	if err := newcomer.Register(ctx, api.Credentials{Username: "alice", Password: "pw2"}); !errors.Is(err, api.ErrValidation) {
+		t.Errorf("taken username: got %v, want ErrValidation", err)
+	}
+	if err := newcomer.Register(ctx, api.Credentials{Username: "bob", Password: "pw2"}); err != nil {
+		t.Fatal(err)
+	}
+	users, err := newcomer.GetAllUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[1].Username != "bob" || users[1].CurrentElo != 1000 {
+		t.Errorf("users after registering: %+v", users)
+	}
+
+	//an expired session is renewed with the credentials the client logged in with
+	s.ExpireSessions()
+	if _, err := newcomer.CurrentGames(ctx); err != nil {
+		t.Errorf("after the session expired: %v", err)
+	}
+}
+
+func TestChallengeStartsGame(t *testing.T) {
+	_, whiteClient, blackClient, game := newGame(t, api.TimeControl{Base: 300, Increment: 2})
+
+	if game.WhiteName != "white" || game.BlackName != "black" || game.Turn != "W" || len(game.Moves) != 0 {
+		t.Errorf("new game: %+v", game)
+	}
+	if game.TimeControl != (api.TimeControl{Base: 300, Increment: 2}) {
+		t.Errorf("time control %+v", game.TimeControl)
+	}
+
+	for _, client := range []*api.Client{whiteClient, blackClient} {
+		games, err := client.CurrentGames(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(games) != 1 || games[0].GameID != game.GameID {
+			t.Errorf("current games %+v", games)
+		}
+	}
+
+	challenges, err := whiteClient.Challenges(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(challenges) != 1 || challenges[0].State != api.ChallengeAccepted || challenges[0].GameID != game.GameID {
+		t.Errorf("challenges %+v", challenges)
+	}
+	if _, err := blackClient.AcceptChallenge(ctx, challenges[0].ChallengeID); !errors.Is(err, api.ErrValidation) {
+		t.Errorf("accepting twice: got %v, want ErrValidation", err)
+	}
+}
+
+func TestRematchChallenge(t *testing.T) {
+	_, whiteClient, blackClient, game := newGame(t, api.TimeControl{Base: 300})
+	rematch := api.ChallengeRequest{ToID: game.WhiteID, Colour: api.ColourWhite, TimeControl: game.TimeControl, RematchOf: game.GameID}
+
+	//only a finished game can be rematched
+	if _, err := blackClient.SendChallenge(ctx, rematch); !errors.Is(err, api.ErrValidation) {
+		t.Errorf("rematch of a game in progress: got %v, want ErrValidation", err)
+	}
+	if err := whiteClient.Resign(ctx, game.GameID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blackClient.SendChallenge(ctx, rematch); err != nil {
+		t.Fatal(err)
+	}
+	notPlayed := rematch
+	notPlayed.RematchOf = 99
+	if _, err := blackClient.SendChallenge(ctx, notPlayed); !errors.Is(err, api.ErrValidation) {
+		t.Errorf("rematch of another game: got %v, want ErrValidation", err)
+	}
+
+	//the opponent sees which game it is a rematch of
+	challenges, err := whiteClient.Challenges(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(challenges) != 2 || challenges[0].RematchOf != 0 || challenges[1].RematchOf != game.GameID {
+		t.Errorf("challenges %+v", challenges)
+	}
+}
+
+func TestNewGame(t *testing.T) {
+	s := fakeserver.New()
+	defer s.Close()
+	s.AddUser("white", "pw")
+	black := s.AddUser("black", "pw")
+	whiteClient := login(t, s, "white")
+
+	//servers without challenges start the game straight away, with the caller as white
+	game, err := whiteClient.NewGame(ctx, black.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.WhiteName != "white" || game.BlackName != "black" || game.Turn != "W" {
+		t.Errorf("new game: %+v", game)
+	}
+	if _, err := whiteClient.NewGame(ctx, 99); !errors.Is(err, api.ErrValidation) {
+		t.Errorf("unknown opponent: got %v, want ErrValidation", err)
+	}
+}
+
+func TestMoveLegality(t *testing.T) {
+	s, whiteClient, blackClient, game := newGame(t, api.TimeControl{})
+
+	illegal := []struct {
+		name   string
+		client *api.Client
+		from   string
+		to     string
+	}{
+		{"out of turn", blackClient, "e7", "e5"},
+		{"opponent's piece", whiteClient, "e7", "e5"},
+		{"empty square", whiteClient, "e4", "e5"},
+		{"pawn three forward", whiteClient, "e2", "e5"},
+		{"pawn sideways", whiteClient, "e2", "d3"},
+		{"bishop through a pawn", whiteClient, "f1", "c4"},
+		{"knight like a bishop", whiteClient, "g1", "e3"},
+		{"taking its own piece", whiteClient, "d1", "d2"},
+	}
+	for _, tc := range illegal {
+		if err := move(tc.client, game.GameID, "P", tc.from, tc.to); !errors.Is(err, api.ErrValidation) {
+			t.Errorf("%s: got %v, want ErrValidation", tc.name, err)
+		}
+	}
+
+	legal := []struct {
+		client *api.Client
+		piece  string
+		from   string
+		to     string
+	}{
+		{whiteClient, "P", "e2", "e4"},
+		{blackClient, "P", "d7", "d5"},
+		{whiteClient, "P", "e4", "d5"},
+		{blackClient, "Q", "d8", "d5"},
+		{whiteClient, "N", "g1", "f3"},
+	}
+	for _, tc := range legal {
+		if err := move(tc.client, game.GameID, tc.piece, tc.from, tc.to); err != nil {
+			t.Fatalf("%s to %s: %v", tc.from, tc.to, err)
+		}
+	}
+
+	got, _ := s.Game(game.GameID)
+	if len(got.Moves) != len(legal) || got.Turn != "B" {
+		t.Errorf("after the moves: turn %s, %d moves", got.Turn, len(got.Moves))
+	}
+}
+
+func TestLastMove(t *testing.T) {
+	_, whiteClient, blackClient, game := newGame(t, api.TimeControl{})
+
+	last, err := blackClient.LastMove(ctx, game.GameID)
+	if err != nil || last != nil {
+		t.Errorf("before any move: got %+v, %v", last, err)
+	}
+
+	if err := move(whiteClient, game.GameID, "P", "e2", "e4"); err != nil {
+		t.Fatal(err)
+	}
+	last, err = blackClient.LastMove(ctx, game.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//e2 is file 4 rank 1, numbered file*8+rank
+	want := api.DbMove{MIndex: 1, GameID: game.GameID, MFrom: 33, MTo: 35, PieceName: "P"}
+	if *last != want {
+		t.Errorf("last move %+v, want %+v", *last, want)
+	}
+
+	if err := whiteClient.Resign(ctx, game.GameID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blackClient.LastMove(ctx, game.GameID); !errors.Is(err, api.ErrGameOver) {
+		t.Errorf("after resigning: got %v, want ErrGameOver", err)
+	}
+}
+
+func TestOffers(t *testing.T) {
+	s, whiteClient, blackClient, game := newGame(t, api.TimeControl{})
+
+	if err := whiteClient.MakeOffer(ctx, game.GameID, api.TakebackOffer); !errors.Is(err, api.ErrValidation) {
+		t.Errorf("takeback with no moves: got %v, want ErrValidation", err)
+	}
+
+	for _, m := range [][2]string{{"e2", "e4"}, {"e7", "e5"}, {"g1", "f3"}} {
+		client := whiteClient
+		if m[0][1] == '7' {
+			client = blackClient
+		}
+		if err := move(client, game.GameID, "P", m[0], m[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//black takes back their own move, which also undoes white's reply
+	if err := blackClient.MakeOffer(ctx, game.GameID, api.TakebackOffer); err != nil {
+		t.Fatal(err)
+	}
+	if err := blackClient.MakeOffer(ctx, game.GameID, api.TakebackOffer); !errors.Is(err, api.ErrValidation) {
+		t.Errorf("second pending takeback: got %v, want ErrValidation", err)
+	}
+	offers, err := whiteClient.Offers(ctx, game.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offers) != 1 || offers[0].By != "B" || offers[0].State != api.OfferPending {
+		t.Fatalf("offers %+v", offers)
+	}
+	if err := blackClient.RespondToOffer(ctx, game.GameID, offers[0].OfferID, true); !errors.Is(err, api.ErrValidation) {
+		t.Errorf("answering your own offer: got %v, want ErrValidation", err)
+	}
+	if err := whiteClient.RespondToOffer(ctx, game.GameID, offers[0].OfferID, true); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := s.Game(game.GameID)
+	if len(got.Moves) != 1 || got.Turn != "B" {
+		t.Errorf("after the takeback: turn %s, %d moves", got.Turn, len(got.Moves))
+	}
+	//the square black moved from has its pawn back
+	if err := move(blackClient, game.GameID, "P", "e7", "e6"); err != nil {
+		t.Errorf("moving the pawn that was taken back: %v", err)
+	}
+
+	//a declined draw leaves the game going, an accepted one ends it
+	if err := whiteClient.MakeOffer(ctx, game.GameID, api.DrawOffer); err != nil {
+		t.Fatal(err)
+	}
+	if err := blackClient.RespondToOffer(ctx, game.GameID, 2, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := whiteClient.MakeOffer(ctx, game.GameID, api.DrawOffer); err != nil {
+		t.Fatal(err)
+	}
+	if err := blackClient.RespondToOffer(ctx, game.GameID, 3, true); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = s.Game(game.GameID)
+	if result := got.Result(); result != "Drawn by agreement" {
+		t.Errorf("result %q", result)
+	}
+	if err := whiteClient.MakeOffer(ctx, game.GameID, api.DrawOffer); !errors.Is(err, api.ErrGameOver) {
+		t.Errorf("offer after the game: got %v, want ErrGameOver", err)
+	}
+}
+
+func TestClock(t *testing.T) {
+	_, whiteClient, blackClient, game := newGame(t, api.TimeControl{Base: 60})
+
+	reading, err := blackClient.Clock(ctx, game.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reading.WhiteMs != 60000 || reading.BlackMs != 60000 || reading.Running != "" {
+		t.Errorf("before the first move: %+v", reading.ClockState)
+	}
+
+	//the clocks start after white's first move, with black's running
+	if err := move(whiteClient, game.GameID, "P", "e2", "e4"); err != nil {
+		t.Fatal(err)
+	}
+	reading, err = blackClient.Clock(ctx, game.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reading.Running != "B" || reading.WhiteMs != 60000 || reading.BlackMs > 60000 || reading.BlackMs < 59000 {
+		t.Errorf("after the first move: %+v", reading.ClockState)
+	}
+	if reading.ServerTimestamp().IsZero() {
+		t.Errorf("server time %q", reading.ServerTime)
+	}
+
+	_, untimedWhite, _, untimed := newGame(t, api.TimeControl{})
+	if _, err := untimedWhite.Clock(ctx, untimed.GameID); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("untimed game: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestClockIncrementAndDelay(t *testing.T) {
+	s, whiteClient, blackClient, game := newGame(t, api.TimeControl{Base: 60, Increment: 2, Delay: 3})
+
+	clock := func() api.ClockState {
+		t.Helper()
+		reading, err := whiteClient.Clock(ctx, game.GameID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reading.ClockState
+	}
+
+	//each move is well inside the delay, so all the time used comes back along with the increment
+	for _, m := range [][2]string{{"e2", "e4"}, {"e7", "e5"}, {"g1", "f3"}} {
+		client := whiteClient
+		if m[0][1] == '7' {
+			client = blackClient
+		}
+		if err := move(client, game.GameID, "P", m[0], m[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//white's first move started the clocks without pressing them
+	if state := clock(); state.Running != "B" || state.WhiteMs != 62000 || state.BlackMs > 62000 || state.BlackMs < 61000 {
+		t.Errorf("after three moves: %+v", state)
+	}
+
+	//taking back Nf3 puts both clocks back to the start of white's turn
+	if err := whiteClient.MakeOffer(ctx, game.GameID, api.TakebackOffer); err != nil {
+		t.Fatal(err)
+	}
+	offers, err := blackClient.Offers(ctx, game.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blackClient.RespondToOffer(ctx, game.GameID, offers[0].OfferID, true); err != nil {
+		t.Fatal(err)
+	}
+	if state := clock(); state.Running != "W" || state.BlackMs != 62000 || state.WhiteMs > 60000 || state.WhiteMs < 59000 {
+		t.Errorf("after the takeback: %+v", state)
+	}
+
+	//taking back every move stops the clocks until white moves again
+	if err := blackClient.MakeOffer(ctx, game.GameID, api.TakebackOffer); err != nil {
+		t.Fatal(err)
+	}
+	if err := whiteClient.RespondToOffer(ctx, game.GameID, 2, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := whiteClient.MakeOffer(ctx, game.GameID, api.TakebackOffer); err != nil {
+		t.Fatal(err)
+	}
+	if err := blackClient.RespondToOffer(ctx, game.GameID, 3, true); err != nil {
+		t.Fatal(err)
+	}
+	if state := clock(); state.Running != "" || state.WhiteMs != 60000 || state.BlackMs != 60000 {
+		t.Errorf("after taking back every move: %+v", state)
+	}
+
+	got, _ := s.Game(game.GameID)
+	for _, tc := range []api.TimeControl{{Base: 60, Delay: -1}, {Base: 60, Delay: 61}} {
+		_, err := whiteClient.SendChallenge(ctx, api.ChallengeRequest{ToID: got.BlackID, Colour: api.ColourWhite, TimeControl: tc})
+		if !errors.Is(err, api.ErrValidation) {
+			t.Errorf("delay %d with base %d: got %v, want ErrValidation", tc.Delay, tc.Base, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jjj333-p/chess-fe-go/api"
+	"github.com/jjj333-p/chess-fe-go/api/replay"
+)
+
+// serverFixtures is where sessions recorded from the real server go, with the client's -record flag.
+const serverFixtures = "testdata/server"
+
+// decodedAs is the type each recorded path decodes into, by the path's pattern.
+var decodedAs = []struct {
+	path *regexp.Regexp
+	into func() any
+}{
+	{regexp.MustCompile(`^/_game/(current|old|live)$`), func() any { return &[]api.DbGame{} }},
+	{regexp.MustCompile(`^/_game/new/\d+$`), func() any { return &api.DbGame{} }},
+	{regexp.MustCompile(`^/_game/\d+/last_move$`), func() any { return &api.DbMove{} }},
+	{regexp.MustCompile(`^/_user/list$`), func() any { return &[]api.DbUser{} }},
+	{regexp.MustCompile(`^/_user/\d+$`), func() any { return &api.DbUser{} }},
+	{regexp.MustCompile(`^/_leaderboard/current$`), func() any { return &[]api.LeaderboardEntry{} }},
+	{regexp.MustCompile(`^/_tournament/list$`), func() any { return &[]api.DbTournament{} }},
+}
+
+/*
+TestServerFixturesDecode decodes every successful response recorded from the
+real server into the type the client reads it as. A response that no longer
+decodes, or has fields the client would drop, fails the test.
+*/
+func TestServerFixturesDecode(t *testing.T) {
+	entries, err := os.ReadDir(serverFixtures)
+	if os.IsNotExist(err) {
+		t.Skip("no session recorded from the real server, run the client with -record api/" + serverFixtures)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := make(map[string]bool)
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(serverFixtures, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fixture replay.Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			t.Fatalf("%s: %v", entry.Name(), err)
+		}
+		if fixture.Method != "GET" || fixture.Status != 200 || len(fixture.Body) == 0 {
+			continue
+		}
+
+		for _, d := range decodedAs {
+			if !d.path.MatchString(fixture.Path) {
+				continue
+			}
+			into := d.into()
+			decoder := json.NewDecoder(bytes.NewReader(fixture.Body))
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(into); err != nil {
+				t.Errorf("%s: decoding %s as %T: %v", entry.Name(), fixture.Path, into, err)
+			}
+			decoded[strings.TrimPrefix(strings.TrimPrefix(fmt.Sprintf("%T", into), "*"), "[]")] = true
+		}
+	}
+
+	for _, name := range []string{"api.DbGame", "api.DbUser", "api.DbTournament", "api.LeaderboardEntry"} {
+		if !decoded[name] {
+			t.Errorf("the recorded session has no response with a %s in it", name)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type DbMove struct {
+	MIndex    int    `json:"mindex"`
+	GameID    int    `json:"game_id"`
+	MFrom     int    `json:"mfrom"`
+	MTo       int    `json:"mto"`
+	PieceName string `json:"piece_name"`
+}
+
+type DbGame struct {
+	GameID         int         `json:"game_id"`
+	Date           string      `json:"date"`
+	Status         string      `json:"status"`
+	WhiteID        int         `json:"white_id"`
+	WhiteName      string      `json:"white_name"`
+	BlackID        int         `json:"black_id"`
+	BlackName      string      `json:"black_name"`
+	WhiteElo       int         `json:"white_elo"`
+	BlackElo       int         `json:"black_elo"`
+	WhiteEloChange int         `json:"white_elo_change"`
+	BlackEloChange int         `json:"black_elo_change"`
+	TID            int         `json:"tid"`
+	Bracket        int         `json:"bracket"`
+	Turn           string      `json:"turn"`
+	TName          string      `json:"tname"`
+	Moves          []DbMove    `json:"moves"`
+	TimeControl    TimeControl `json:"time_control"`
+	Rated          bool        `json:"rated"`
+	// EndReason says how a finished game ended, such as EndResignation. It is empty for a win on the board.
+	EndReason string `json:"end_reason"`
+}
+
+// Values of DbGame.Status. An ongoing game has an empty status.
+const (
+	StatusWhiteWon = "W"
+	StatusBlackWon = "B"
+	StatusDraw     = "D"
+)
+
+// Values of DbGame.EndReason.
+const (
+	EndResignation = "resignation"
+	EndAgreement   = "agreement"
+	// EndTimeout is when the player to move ran out of time.
+	EndTimeout = "timeout"
+)
+
+// Result describes how the game stands, eg "White won by resignation", for showing to the user.
+func (g *DbGame) Result() string {
+	var result string
+	switch g.Status {
+	case "":
+		return "In progress"
+	case StatusWhiteWon:
+		result = "White won"
+	case StatusBlackWon:
+		result = "Black won"
+	case StatusDraw:
+		result = "Drawn"
+	default:
+		return "Unknown status \"" + g.Status + "\""
+	}
+
+	switch g.EndReason {
+	case "":
+	case EndTimeout:
+		result += " on time"
+	default:
+		result += " by " + g.EndReason
+	}
+	return result
+}
+
+// MoveRequest is the body of a move submission. Squares are (file, rank) pairs.
+type MoveRequest struct {
+	PieceID string `json:"piece_id"`
+	MFrom   [2]int `json:"mfrom"`
+	MTo     [2]int `json:"mto"`
+}
+
+// CurrentGames lists the ongoing games of the logged in user.
+func (c *Client) CurrentGames(ctx context.Context) ([]DbGame, error) {
+	var games []DbGame
+	if err := c.GetJSON(ctx, "/_game/current", &games); err != nil {
+		return nil, err
+	}
+	return games, nil
+}
+
+/*
+LiveGames lists the ongoing games of every player on the server, for finding
+a game to watch.
+*/
+func (c *Client) LiveGames(ctx context.Context) ([]DbGame, error) {
+	var games []DbGame
+	if err := c.GetJSON(ctx, "/_game/live", &games); err != nil {
+		return nil, err
+	}
+	return games, nil
+}
+
+// OldGames lists the finished games of the logged in user.
+func (c *Client) OldGames(ctx context.Context) ([]DbGame, error) {
+	var games []DbGame
+	if err := c.GetJSON(ctx, "/_game/old", &games); err != nil {
+		return nil, err
+	}
+	return games, nil
+}
+
+/*
+NewGame starts a game against the user with opponentUID straight away, with the
+logged in user as white. It is for servers without challenges, which answer
+SendChallenge with an error matching ErrNotFound.
+*/
+func (c *Client) NewGame(ctx context.Context, opponentUID int) (*DbGame, error) {
+	var game DbGame
+	if err := c.GetJSON(ctx, fmt.Sprintf("/_game/new/%d", opponentUID), &game); err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+/*
+GetGame fetches a game with its full move list. The server has no endpoint for
+a single game, so it is looked up in the current games and then the old ones,
+and last of all in the live games, for games being watched.
+*/
+func (c *Client) GetGame(ctx context.Context, gameID int) (*DbGame, error) {
+	for _, list := range []func(context.Context) ([]DbGame, error){c.CurrentGames, c.OldGames, c.LiveGames} {
+		games, err := list(ctx)
+		if errors.Is(err, ErrNotFound) {
+			//servers without live games
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := range games {
+			if games[i].GameID == gameID {
+				return &games[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("game %d not found", gameID)
+}
+
+/*
+LastMove fetches the most recent move of a game. It returns nil with no error
+if no move has been made yet, and an error matching ErrGameOver once the game
+has finished.
+*/
+func (c *Client) LastMove(ctx context.Context, gameID int) (*DbMove, error) {
+	var move DbMove
+	if err := c.GetJSON(ctx, fmt.Sprintf("/_game/%d/last_move", gameID), &move); err != nil {
+		return nil, err
+	}
+
+	if move.GameID == 0 {
+		return nil, nil
+	}
+
+	return &move, nil
+}
+
+// Resign ends a game as a loss for the logged in user.
+func (c *Client) Resign(ctx context.Context, gameID int) error {
+	return c.PostJSON(ctx, fmt.Sprintf("/_game/%d/resign", gameID), nil, nil)
+}
+
+// MakeMove submits a move in a game.
+func (c *Client) MakeMove(ctx context.Context, gameID int, move MoveRequest) error {
+	return c.PostJSON(ctx, fmt.Sprintf("/_game/%d/move", gameID), move, nil)
+}
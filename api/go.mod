@@ -0,0 +1,3 @@
+module github.com/jjj333-p/chess-fe-go/api
+
+go 1.23.8
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"context"
+)
+
+type LeaderboardEntry struct {
+	UID      int     `json:"uid"`
+	Username string  `json:"username"`
+	Rating   float64 `json:"rating"`
+	Rank     int     `json:"rank"`
+}
+
+// GetCurrentLeaderboard fetches the current player rankings.
+func (c *Client) GetCurrentLeaderboard(ctx context.Context) ([]LeaderboardEntry, error) {
+	var leaderboard []LeaderboardEntry
+	if err := c.GetJSON(ctx, "/_leaderboard/current", &leaderboard); err != nil {
+		return nil, err
+	}
+	return leaderboard, nil
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+// Kinds of Offer.
+const (
+	DrawOffer = "draw"
+	// TakebackOffer asks to undo the requester's last move, along with the opponent's reply to it if there was one.
+	TakebackOffer = "takeback"
+)
+
+// States of an Offer.
+const (
+	OfferPending  = "pending"
+	OfferAccepted = "accepted"
+	OfferDeclined = "declined"
+)
+
+/*
+Offer is a proposal one player in a game makes to the other, such as a draw.
+It stays pending until the other player accepts or declines it, or until the
+game moves on: making a move declines any offer the opponent has pending, and
+any takeback request, since the move it was for is no longer the last one.
+*/
+type Offer struct {
+	OfferID int    `json:"offer_id"`
+	GameID  int    `json:"game_id"`
+	Kind    string `json:"kind"`
+	// By is the colour of the player who made the offer, "W" or "B".
+	By    string `json:"by"`
+	State string `json:"state"`
+}
+
+// MakeOffer proposes something of the given kind, such as DrawOffer, to the opponent.
+func (c *Client) MakeOffer(ctx context.Context, gameID int, kind string) error {
+	body := map[string]string{"kind": kind}
+	return c.PostJSON(ctx, fmt.Sprintf("/_game/%d/offer", gameID), body, nil)
+}
+
+// RespondToOffer accepts or declines an offer made by the opponent.
+func (c *Client) RespondToOffer(ctx context.Context, gameID int, offerID int, accept bool) error {
+	response := "decline"
+	if accept {
+		response = "accept"
+	}
+	return c.PostJSON(ctx, fmt.Sprintf("/_game/%d/offer/%d/%s", gameID, offerID, response), nil, nil)
+}
+
+// Offers lists every offer made in a game, oldest first, including ones already answered.
+func (c *Client) Offers(ctx context.Context, gameID int) ([]Offer, error) {
+	var offers []Offer
+	if err := c.GetJSON(ctx, fmt.Sprintf("/_game/%d/offers", gameID), &offers); err != nil {
+		return nil, err
+	}
+	return offers, nil
+}
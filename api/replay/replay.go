@@ -0,0 +1,324 @@
+/*
+Package replay records the client's traffic with the server to a fixture
+directory and plays it back, so decoding can be checked against real server
+output without the server. Install a Transport as the Transport of an
+api.Client's HTTP client.
+
+Session tokens and passwords are redacted before anything is written. The
+game event stream is recorded as far as it was read, once it is closed.
+*/
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces secrets in recorded fixtures.
+const Redacted = "REDACTED"
+
+// Fixture is one recorded request and the server's response to it.
+type Fixture struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Query is the raw query string, without the leading "?".
+	Query         string          `json:"query,omitempty"`
+	RequestHeader http.Header     `json:"request_header,omitempty"`
+	RequestBody   json.RawMessage `json:"request_body,omitempty"`
+	Status        int             `json:"status"`
+	Header        http.Header     `json:"header,omitempty"`
+	//Body is the response when it is json, otherwise it is kept as text in BodyText
+	Body     json.RawMessage `json:"body,omitempty"`
+	BodyText string          `json:"body_text,omitempty"`
+}
+
+/*
+Transport is an http.RoundTripper that either records every exchange through
+Next into Dir, or answers requests from the fixtures already in Dir.
+*/
+type Transport struct {
+	Dir string
+	// Next sends requests while recording. It is nil when replaying.
+	Next http.RoundTripper
+
+	mu  sync.Mutex
+	seq int
+	//replay fixtures by key, and how many of each have been served
+	fixtures map[string][]*Fixture
+	served   map[string]int
+}
+
+// NewRecorder records exchanges sent through next into dir, after any fixtures already there.
+func NewRecorder(dir string, next http.RoundTripper) (*Transport, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating fixture directory: %v", err)
+	}
+	names, err := fixtureNames(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Transport{Dir: dir, Next: next, seq: len(names)}, nil
+}
+
+/*
+NewReplayer answers requests from the fixtures in dir. Requests with the same
+method, path and query get their recorded responses in order, and the last one
+again once they run out, so polling loops keep working.
+*/
+func NewReplayer(dir string) (*Transport, error) {
+	names, err := fixtureNames(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Transport{
+		Dir:      dir,
+		fixtures: make(map[string][]*Fixture),
+		served:   make(map[string]int),
+	}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("error reading fixture: %v", err)
+		}
+		fixture := &Fixture{}
+		if err := json.Unmarshal(data, fixture); err != nil {
+			return nil, fmt.Errorf("error parsing fixture %s: %v", name, err)
+		}
+		key := fixtureKey(fixture.Method, fixture.Path, fixture.Query)
+		t.fixtures[key] = append(t.fixtures[key], fixture)
+	}
+	return t, nil
+}
+
+// fixtureNames lists the fixture files in dir in recording order.
+func fixtureNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading fixture directory: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// fixtureKey is what a request is matched to its fixtures by, eg "GET /_game/3/chat?after=5".
+func fixtureKey(method string, path string, query string) string {
+	key := method + " " + path
+	if query != "" {
+		key += "?" + query
+	}
+	return key
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Next == nil {
+		return t.replay(req)
+	}
+	return t.record(req)
+}
+
+func (t *Transport) replay(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	key := fixtureKey(req.Method, req.URL.Path, req.URL.RawQuery)
+
+	t.mu.Lock()
+	fixtures := t.fixtures[key]
+	if len(fixtures) == 0 {
+		t.mu.Unlock()
+		return nil, errors.New("no fixture recorded for " + key)
+	}
+	fixture := fixtures[min(t.served[key], len(fixtures)-1)]
+	t.served[key]++
+	t.mu.Unlock()
+
+	body := []byte(fixture.BodyText)
+	if fixture.Body != nil {
+		body = fixture.Body
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Status, http.StatusText(fixture.Status)),
+		StatusCode:    fixture.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        fixture.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *Transport) record(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := t.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	//an event stream stays open for the whole game, so it is saved when the client closes it
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		resp.Body = &streamRecorder{
+			ReadCloser: resp.Body,
+			save: func(streamed []byte) {
+				t.saveExchange(req, reqBody, resp, streamed)
+			},
+		}
+		return resp, nil
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	t.saveExchange(req, reqBody, resp, respBody)
+	return resp, nil
+}
+
+// saveExchange saves one request and the server's response to it as a fixture.
+func (t *Transport) saveExchange(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte) {
+	fixture := &Fixture{
+		Method:        req.Method,
+		Path:          req.URL.Path,
+		Query:         req.URL.RawQuery,
+		RequestHeader: redactHeader(req.Header),
+		Status:        resp.StatusCode,
+		Header:        redactHeader(resp.Header),
+	}
+	//redacting can change the body length, replay works it out again
+	fixture.Header.Del("Content-Length")
+	if len(reqBody) > 0 {
+		fixture.RequestBody = redactJSON(reqBody)
+	}
+	if json.Valid(respBody) {
+		fixture.Body = redactJSON(respBody)
+	} else {
+		fixture.BodyText = string(respBody)
+	}
+
+	//a failed write shouldn't break the session being recorded
+	if err := t.save(fixture); err != nil {
+		fmt.Println("Error saving fixture:", err)
+	}
+}
+
+// streamRecorder keeps what is read from a response body and saves it when the body is closed.
+type streamRecorder struct {
+	io.ReadCloser
+	save func(streamed []byte)
+
+	buf  bytes.Buffer
+	once sync.Once
+}
+
+func (r *streamRecorder) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.buf.Write(p[:n])
+	return n, err
+}
+
+func (r *streamRecorder) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(func() {
+		r.save(r.buf.Bytes())
+	})
+	return err
+}
+
+func (t *Transport) save(fixture *Fixture) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding fixture: %v", err)
+	}
+
+	t.mu.Lock()
+	t.seq++
+	seq := t.seq
+	t.mu.Unlock()
+
+	//eg 0003_POST__game_12_move.json
+	name := fmt.Sprintf("%04d_%s%s.json", seq, fixture.Method, strings.ReplaceAll(fixture.Path, "/", "_"))
+	return os.WriteFile(filepath.Join(t.Dir, name), data, 0o644)
+}
+
+func redactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+	for name := range redacted {
+		if isSecret(name) {
+			redacted[name] = []string{Redacted}
+		}
+	}
+	return redacted
+}
+
+func isSecret(name string) bool {
+	switch strings.ToLower(name) {
+	case "token", "password", "authorization", "cookie", "set-cookie":
+		return true
+	}
+	return false
+}
+
+// redactJSON blanks out token and password fields anywhere in a json document.
+func redactJSON(data []byte) json.RawMessage {
+	//keep numbers as written rather than round tripping them through float64
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return data
+	}
+	redacted, err := json.Marshal(redactValue(doc))
+	if err != nil {
+		return data
+	}
+	return redacted
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if isSecret(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = redactValue(field)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return v
+}
@@ -0,0 +1,105 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReplayMatchesQuery(t *testing.T) {
+	dir := t.TempDir()
+
+	//record the same path with two different queries
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"after":"`+r.URL.Query().Get("after")+`"}`)
+	}))
+	defer server.Close()
+
+	recorder, err := NewRecorder(dir, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the after the server saw, from the response
+	get := func(client *http.Client, query string) string {
+		t.Helper()
+		resp, err := client.Get(server.URL + "/_game/3/chat" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body struct {
+			After string `json:"after"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return body.After
+	}
+	recording := &http.Client{Transport: recorder}
+	get(recording, "?after=0")
+	get(recording, "?after=5")
+
+	replayer, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replaying := &http.Client{Transport: replayer}
+	//asked in the other order, each still gets its own response
+	if after := get(replaying, "?after=5"); after != "5" {
+		t.Errorf("after=5 got the response for after=%s", after)
+	}
+	if after := get(replaying, "?after=0"); after != "0" {
+		t.Errorf("after=0 got the response for after=%s", after)
+	}
+	if _, err := replaying.Get(server.URL + "/_game/3/chat?after=9"); err == nil {
+		t.Error("a query that was never recorded was answered")
+	}
+}
+
+func TestRecordEventStream(t *testing.T) {
+	dir := t.TempDir()
+
+	//the stream stays open after its first event, like a game that has not ended
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "event: move\ndata: {}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	recorder, err := NewRecorder(dir, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: recorder}).Get(server.URL + "/_game/3/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != "event: move\n" {
+		t.Fatalf("read %q, %v from the recorded stream", line, err)
+	}
+	resp.Body.Close()
+
+	replayer, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = (&http.Client{Transport: replayer}).Get(server.URL + "/_game/3/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "event: move\ndata: {}\n\n" {
+		t.Errorf("replayed stream %q", body)
+	}
+}
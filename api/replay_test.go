@@ -0,0 +1,152 @@
+package api_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jjj333-p/chess-fe-go/api"
+	"github.com/jjj333-p/chess-fe-go/api/replay"
+)
+
+/*
+replayClient is a client logged in against the session in testdata/fakeserver.
+That session was recorded from api/fakeserver, so it checks the replaying and
+the fields as the fake has them. Decoding the real server's responses is
+checked by TestServerFixturesDecode.
+*/
+func replayClient(t *testing.T) *api.Client {
+	t.Helper()
+	transport, err := replay.NewReplayer("testdata/fakeserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := api.NewClient("http://replay.invalid")
+	client.HTTP.Transport = transport
+	if err := client.Login(context.Background(), api.Credentials{Username: "magnus", Password: "pw"}); err != nil {
+		t.Fatalf("logging in: %v", err)
+	}
+	return client
+}
+
+func TestReplayGames(t *testing.T) {
+	client := replayClient(t)
+	ctx := context.Background()
+
+	current, err := client.CurrentGames(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCurrent := []api.DbGame{{
+		GameID:    2,
+		Date:      "2026-10-18",
+		WhiteID:   2,
+		WhiteName: "hikaru",
+		BlackID:   1,
+		BlackName: "magnus",
+		WhiteElo:  990,
+		BlackElo:  1010,
+		Turn:      "B",
+		Moves: []api.DbMove{
+			{MIndex: 1, GameID: 2, MFrom: 48, MTo: 42, PieceName: "N"},
+		},
+	}}
+	if !reflect.DeepEqual(current, wantCurrent) {
+		t.Errorf("current games\n got %+v\nwant %+v", current, wantCurrent)
+	}
+
+	//not a current game, so GetGame goes on to the old games
+	game, err := client.GetGame(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOld := api.DbGame{
+		GameID:    1,
+		Date:      "2026-10-18",
+		Status:    api.StatusWhiteWon,
+		WhiteID:   1,
+		WhiteName: "magnus",
+		BlackID:   2,
+		BlackName: "hikaru",
+		WhiteElo:  1000,
+		BlackElo:  1000,
+		Turn:      "W",
+		Moves: []api.DbMove{
+			{MIndex: 1, GameID: 1, MFrom: 33, MTo: 35, PieceName: "P"},
+			{MIndex: 2, GameID: 1, MFrom: 38, MTo: 36, PieceName: "P"},
+		},
+		TimeControl: api.TimeControl{Base: 600, Increment: 5},
+		Rated:       true,
+		EndReason:   api.EndResignation,
+	}
+	if !reflect.DeepEqual(*game, wantOld) {
+		t.Errorf("old game\n got %+v\nwant %+v", *game, wantOld)
+	}
+	if result := game.Result(); result != "White won by resignation" {
+		t.Errorf("result %q", result)
+	}
+}
+
+func TestReplayUsers(t *testing.T) {
+	client := replayClient(t)
+	ctx := context.Background()
+
+	magnus := api.DbUser{UID: 1, Username: "magnus", CurrentElo: 1010, PeakElo: 1010, GamesWon: 1, TotalGames: 1, WinStreak: 1}
+	hikaru := api.DbUser{UID: 2, Username: "hikaru", CurrentElo: 990, PeakElo: 1000, GamesLost: 1, TotalGames: 1, LoseStreak: 1}
+
+	users, err := client.GetAllUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []api.DbUser{magnus, hikaru}; !reflect.DeepEqual(users, want) {
+		t.Errorf("users\n got %+v\nwant %+v", users, want)
+	}
+
+	user, err := client.GetUser(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*user, hikaru) {
+		t.Errorf("user\n got %+v\nwant %+v", *user, hikaru)
+	}
+}
+
+func TestReplayLeaderboard(t *testing.T) {
+	client := replayClient(t)
+
+	leaderboard, err := client.GetCurrentLeaderboard(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []api.LeaderboardEntry{
+		{UID: 1, Username: "magnus", Rating: 1010, Rank: 1},
+		{UID: 2, Username: "hikaru", Rating: 990, Rank: 2},
+	}
+	if !reflect.DeepEqual(leaderboard, want) {
+		t.Errorf("leaderboard\n got %+v\nwant %+v", leaderboard, want)
+	}
+}
+
+func TestReplayTournaments(t *testing.T) {
+	client := replayClient(t)
+
+	tournaments, err := client.GetTournaments(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []api.DbTournament{{
+		TID:         7,
+		Name:        "Autumn Open",
+		StartDate:   "2026-10-01",
+		EndDate:     "2026-10-31",
+		MinElo:      800,
+		MaxElo:      1600,
+		Status:      "open",
+		Bracket:     2,
+		BracketDate: "2026-10-15",
+		CanRegister: true,
+	}}
+	if !reflect.DeepEqual(tournaments, want) {
+		t.Errorf("tournaments\n got %+v\nwant %+v", tournaments, want)
+	}
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+type DbTournament struct {
+	TID         int    `json:"tid"`
+	Name        string `json:"name"`
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date"`
+	MinElo      int    `json:"min_elo"`
+	MaxElo      int    `json:"max_elo"`
+	Status      string `json:"status"`
+	Bracket     int    `json:"bracket"`
+	BracketDate string `json:"bracket_date"`
+	CanRegister bool   `json:"can_register"`
+}
+
+// GetTournaments lists all tournaments.
+func (c *Client) GetTournaments(ctx context.Context) ([]DbTournament, error) {
+	var tournaments []DbTournament
+	if err := c.GetJSON(ctx, "/_tournament/list", &tournaments); err != nil {
+		return nil, err
+	}
+	return tournaments, nil
+}
+
+// RegisterForTournament signs the logged in user up for a tournament.
+func (c *Client) RegisterForTournament(ctx context.Context, tournamentID int) error {
+	return c.GetJSON(ctx, fmt.Sprintf("/_tournament/register/%d", tournamentID), nil)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+type DbUser struct {
+	UID         int    `json:"uid"`
+	GamesLost   int    `json:"games_lost"`
+	GamesWon    int    `json:"games_won"`
+	GamesDraw   int    `json:"games_draw"`
+	WinStreak   int    `json:"win_streak"`
+	LoseStreak  int    `json:"lose_streak"`
+	CurrentElo  int    `json:"current_elo"`
+	TotalGames  int    `json:"total_games"`
+	Username    string `json:"username"`
+	PeakElo     int    `json:"peak_elo"`
+	PeakRank    int    `json:"peak_rank"`
+	CurrentRank int    `json:"current_rank"`
+}
+
+// GetUser fetches the profile of a single user.
+func (c *Client) GetUser(ctx context.Context, uid int) (*DbUser, error) {
+	var user DbUser
+	if err := c.GetJSON(ctx, fmt.Sprintf("/_user/%d", uid), &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetAllUsers lists every user on the server.
+func (c *Client) GetAllUsers(ctx context.Context) ([]DbUser, error) {
+	var users []DbUser
+	if err := c.GetJSON(ctx, "/_user/list", &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/jjj333-p/chess-fe-go/api"
+	"github.com/jjj333-p/chess-fe-go/gameModes"
+)
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 32
+	minPasswordLength = 8
+)
+
+const registrationRules = "Usernames are 3 to 32 letters, numbers, '.', '_' or '-'.\nPasswords need at least 8 characters."
+
+/*
+validateCredentials checks what was typed into the auth form before it is sent.
+Logging in only needs both fields filled in, as older accounts may not follow
+the rules new registrations are held to.
+*/
+func validateCredentials(username string, password string, confirm string, registerInstead bool) error {
+	if username == "" {
+		return errors.New("please enter a username")
+	}
+	if password == "" {
+		return errors.New("please enter a password")
+	}
+	if !registerInstead {
+		return nil
+	}
+
+	if len(username) < minUsernameLength || len(username) > maxUsernameLength {
+		return fmt.Errorf("usernames must be %d to %d characters long", minUsernameLength, maxUsernameLength)
+	}
+	for _, r := range username {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-') {
+			return fmt.Errorf("usernames cannot contain %q", r)
+		}
+	}
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("passwords must be at least %d characters long", minPasswordLength)
+	}
+	if password != confirm {
+		return errors.New("the passwords do not match")
+	}
+	return nil
+}
+
+/*
+authenticate logs in, or registers a new account, with creds. The error is
+meant to be shown to the user, so a message from the server is passed on
+exactly as the server sent it.
+*/
+func authenticate(ctx context.Context, client *api.Client, creds api.Credentials, registerInstead bool) error {
+	var err error
+	if registerInstead {
+		err = client.Register(ctx, creds)
+	} else {
+		err = client.Login(ctx, creds)
+	}
+	if err == nil {
+		return nil
+	}
+	fmt.Println("Error during authentication:", err)
+
+	var statusErr *api.StatusError
+	if errors.As(err, &statusErr) && statusErr.Message != "" {
+		return errors.New(statusErr.Message)
+	}
+	if errors.Is(err, api.ErrUnauthorized) {
+		if registerInstead {
+			return errors.New("the server refused the registration")
+		}
+		return errors.New("invalid username or password")
+	}
+	return err
+}
+
+/*
+authWindow shows the login form, or the registration form if registerInstead,
+and blocks until it closes. On success the client holds the new session,
+account has the credentials, and the session is remembered if asked for.
+*/
+func authWindow(config *clientConfig, profile *serverProfile, client *api.Client, account *gameModes.AccountData, registerInstead bool) {
+	authApp := app.New()
+	windowTitle := "Login"
+	if registerInstead {
+		windowTitle = "Register"
+	}
+	formWindow := authApp.NewWindow(windowTitle)
+
+	//stop a request in flight if the window is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	formWindow.SetOnClosed(cancel)
+
+	usernameEntry := widget.NewEntry()
+	usernameEntry.SetPlaceHolder("Username")
+	usernameEntry.SetText(profile.LastUsername)
+	passwordEntry := widget.NewPasswordEntry()
+	passwordEntry.SetPlaceHolder("Password")
+	confirmEntry := widget.NewPasswordEntry()
+	confirmEntry.SetPlaceHolder("Confirm password")
+
+	rememberPasswordCheck := widget.NewCheck("Also remember password", nil)
+	rememberPasswordCheck.Disable()
+	rememberCheck := widget.NewCheck("Remember me", func(checked bool) {
+		if checked {
+			rememberPasswordCheck.Enable()
+		} else {
+			rememberPasswordCheck.SetChecked(false)
+			rememberPasswordCheck.Disable()
+		}
+	})
+
+	loadingSpinner := widget.NewProgressBarInfinite()
+	loadingSpinner.Hide()
+
+	var submitBtn *widget.Button
+	inputs := []fyne.Disableable{usernameEntry, passwordEntry, confirmEntry, rememberCheck}
+	setBusy := func(busy bool) {
+		for _, input := range inputs {
+			if busy {
+				input.Disable()
+			} else {
+				input.Enable()
+			}
+		}
+		if busy {
+			rememberPasswordCheck.Disable()
+			submitBtn.Disable()
+			loadingSpinner.Show()
+		} else {
+			if rememberCheck.Checked {
+				rememberPasswordCheck.Enable()
+			}
+			submitBtn.Enable()
+			loadingSpinner.Hide()
+		}
+	}
+
+	submitting := false
+	submit := func() {
+		//enter can be pressed again while the request is out
+		if submitting {
+			return
+		}
+
+		creds := api.Credentials{
+			Username: strings.TrimSpace(usernameEntry.Text),
+			Password: passwordEntry.Text,
+		}
+		if err := validateCredentials(creds.Username, creds.Password, confirmEntry.Text, registerInstead); err != nil {
+			dialog.ShowError(err, formWindow)
+			return
+		}
+
+		submitting = true
+		setBusy(true)
+		remember, rememberPassword := rememberCheck.Checked, rememberPasswordCheck.Checked
+
+		//off the ui thread so the spinner keeps moving
+		go func() {
+			err := authenticate(ctx, client, creds, registerInstead)
+			if ctx.Err() != nil {
+				return
+			}
+
+			fyne.Do(func() {
+				submitting = false
+				setBusy(false)
+
+				if err != nil {
+					dialog.ShowError(err, formWindow)
+					return
+				}
+
+				account.Cred = creds
+				profile.LastUsername = creds.Username
+				if err := config.save(); err != nil {
+					fmt.Println("Error saving config:", err)
+				}
+
+				if remember {
+					session := &savedSession{
+						ServerURL: client.BaseURL,
+						Username:  creds.Username,
+						Token:     client.Token(),
+					}
+					if rememberPassword {
+						encrypted, err := encryptPassword(config, creds.Password)
+						if err != nil {
+							fmt.Println("Error encrypting password:", err)
+						} else {
+							session.Password = encrypted
+						}
+					}
+					if err := saveSession(config, session); err != nil {
+						fmt.Println("Error saving session:", err)
+					}
+				} else {
+					clearSession(config)
+				}
+
+				fmt.Println(windowTitle, "successful!")
+				formWindow.Close()
+			})
+		}()
+	}
+
+	submitBtn = widget.NewButton(windowTitle, submit)
+	submitBtn.Importance = widget.HighImportance
+
+	//enter in any field submits the form
+	usernameEntry.OnSubmitted = func(string) { submit() }
+	passwordEntry.OnSubmitted = func(string) { submit() }
+	confirmEntry.OnSubmitted = func(string) { submit() }
+
+	authVBox := container.NewVBox(
+		layout.NewSpacer(),
+		usernameEntry,
+		passwordEntry,
+	)
+	if registerInstead {
+		rulesLabel := widget.NewLabel(registrationRules)
+		rulesLabel.Wrapping = fyne.TextWrapWord
+		authVBox.Add(confirmEntry)
+		authVBox.Add(rulesLabel)
+	}
+	authVBox.Add(rememberCheck)
+	authVBox.Add(rememberPasswordCheck)
+	authVBox.Add(submitBtn)
+	authVBox.Add(loadingSpinner)
+	authVBox.Add(layout.NewSpacer())
+
+	formWindow.SetContent(authVBox)
+	formWindow.Resize(fyne.NewSize(300, 200))
+	formWindow.Canvas().Focus(passwordEntry)
+	if usernameEntry.Text == "" {
+		formWindow.Canvas().Focus(usernameEntry)
+	}
+	formWindow.ShowAndRun()
+}
@@ -7,9 +7,14 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
+	"image/color"
 	"strconv"
+	"sync"
 )
 
+// highlightColor is laid over the squares of a highlighted move.
+var highlightColor = color.NRGBA{R: 80, G: 160, B: 255, A: 120}
+
 type ChessPiece struct {
 	Black     bool
 	PieceType string
@@ -71,13 +76,41 @@ func NewDefaultPieceForPosition(rank, file int) *ChessPiece {
 }
 
 type ChessTile struct {
-	Piece    *ChessPiece
-	Name     string
-	moveChan *chan *Location
-	Button   *widget.Button
-	uiTop    *fyne.Container
-	UiEL     *fyne.Container
-	BgColor  *canvas.Image
+	Piece     *ChessPiece
+	Name      string
+	selection *selection
+	Button    *widget.Button
+	uiTop     *fyne.Container
+	UiEL      *fyne.Container
+	BgColor   *canvas.Image
+	//highlight is shown over the background to mark the tile
+	highlight  *canvas.Rectangle
+	rightClick *rightClickCatcher
+}
+
+/*
+rightClickCatcher sits under a tile's piece and button to pick up right clicks,
+which buttons ignore. Left clicks still go to the button.
+*/
+type rightClickCatcher struct {
+	widget.BaseWidget
+	onTapped func()
+}
+
+func newRightClickCatcher() *rightClickCatcher {
+	catcher := &rightClickCatcher{}
+	catcher.ExtendBaseWidget(catcher)
+	return catcher
+}
+
+func (self *rightClickCatcher) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(canvas.NewRectangle(color.Transparent))
+}
+
+func (self *rightClickCatcher) TappedSecondary(*fyne.PointEvent) {
+	if self.onTapped != nil {
+		self.onTapped()
+	}
 }
 
 /*
@@ -115,7 +148,7 @@ func (self *ChessTile) AssembleUI(refresh bool) {
 	if refresh {
 		self.UiEL.Refresh()
 	} else {
-		self.UiEL = container.NewStack(self.BgColor, self.uiTop)
+		self.UiEL = container.NewStack(self.BgColor, self.highlight, self.rightClick, self.uiTop)
 	}
 }
 
@@ -139,14 +172,15 @@ func initChessTileAtPos(rank, file int) *ChessTile {
 	newTile.Button = widget.NewButton("", func() {
 		fmt.Printf("Clicked square %c%d\n", fileChar, rankNum)
 
-		//moveChan of the tile object is basically used as a radio to broadcast a button press over
-		if newTile.moveChan == nil {
+		//selection of the tile object is basically used as a radio to broadcast a button press over
+		if newTile.selection == nil {
 			//fmt.Println("Nothing to click")
 			panic("There is no action behind this button. Why????")
 		} else {
-			*newTile.moveChan <- &Location{
-				rank,
-				file,
+			//a tile left over from an abandoned selection has nobody to tell
+			select {
+			case newTile.selection.picked <- &Location{rank, file}:
+			case <-newTile.selection.done:
 			}
 		}
 	})
@@ -164,19 +198,132 @@ func initChessTileAtPos(rank, file int) *ChessTile {
 	}
 	newTile.BgColor.SetMinSize(fyne.NewSize(70, 70))
 
+	newTile.highlight = canvas.NewRectangle(highlightColor)
+	newTile.highlight.Hide()
+	newTile.rightClick = newRightClickCatcher()
+
 	newTile.AssembleUI(false)
 
 	return newTile
 }
 
+// selection is one tile being picked, for PrepareForMove or a chooser.
+type selection struct {
+	picked chan *Location
+	//closed once the selection is over, whether a tile was picked or not
+	done chan struct{}
+}
+
 type ChessBoard struct {
 	Grid    *fyne.Container
 	Tiles   [8][8]*ChessTile
 	discard []*ChessPiece
+	closed  chan struct{}
+	//the selection in progress, if any
+	selectionMu sync.Mutex
+	current     *selection
+	// OnSecondaryTap is called on the ui thread when any tile is right clicked.
+	OnSecondaryTap func()
+}
+
+/*
+Close releases the goroutines waiting on a selection from PrepareForMove or
+MoveChooser, for when the window showing the board goes away.
+The board should not be used to pick moves afterwards.
+*/
+func (self *ChessBoard) Close() {
+	select {
+	case <-self.closed:
+	default:
+		close(self.closed)
+	}
+}
+
+/*
+newSelection abandons the selection in progress, if there is one, and starts
+another. The tile picked for it is passed on to the returned chan.
+*/
+func (self *ChessBoard) newSelection() (*selection, chan *Location) {
+	self.selectionMu.Lock()
+	if self.current != nil {
+		close(self.current.done)
+	}
+	sel := &selection{picked: make(chan *Location), done: make(chan struct{})}
+	self.current = sel
+	self.selectionMu.Unlock()
+
+	moveChan := make(chan *Location)
+	go self.forwardSelection(sel, moveChan)
+	return sel, moveChan
 }
 
-// DisableAllBtn disables all buttons on the board. Simple as.
+// endSelection marks sel over, unless another selection has already replaced it.
+func (self *ChessBoard) endSelection(sel *selection) {
+	self.selectionMu.Lock()
+	defer self.selectionMu.Unlock()
+	if self.current == sel {
+		close(sel.done)
+		self.current = nil
+	}
+}
+
+/*
+forwardSelection waits for a tile to be picked for sel, disables the buttons,
+and passes the location on to moveChan, giving up if sel is abandoned for
+another selection or the board is closed.
+*/
+func (self *ChessBoard) forwardSelection(sel *selection, moveChan chan *Location) {
+	defer self.endSelection(sel)
+
+	var l *Location
+	select {
+	case l = <-sel.picked:
+	case <-sel.done:
+		return
+	case <-self.closed:
+		return
+	}
+
+	self.disableTiles()
+
+	select {
+	case moveChan <- l:
+	case <-sel.done:
+	case <-self.closed:
+	}
+}
+
+/*
+HighlightMove marks the two tiles of a move, such as a premove waiting to be
+played, clearing any earlier highlight. A nil move just clears it.
+Must be called on the ui thread.
+*/
+func (self *ChessBoard) HighlightMove(move *Move) {
+	for _, rankSlice := range self.Tiles {
+		for _, tile := range rankSlice {
+			tile.highlight.Hide()
+		}
+	}
+	if move == nil {
+		return
+	}
+	self.Tiles[move.From.Rank][move.From.File].highlight.Show()
+	self.Tiles[move.To.Rank][move.To.File].highlight.Show()
+}
+
+// DisableAllBtn disables all buttons on the board, abandoning any selection in progress.
 func (self *ChessBoard) DisableAllBtn() {
+	self.selectionMu.Lock()
+	current := self.current
+	self.selectionMu.Unlock()
+	if current != nil {
+		self.endSelection(current)
+	}
+	self.disableTiles()
+}
+
+// disableTiles disables all buttons on the board. Simple as.
+func (self *ChessBoard) disableTiles() {
 	for _, fileSlice := range self.Tiles {
 		for _, tile := range fileSlice {
 			fyne.Do(func() {
@@ -186,7 +333,7 @@ func (self *ChessBoard) DisableAllBtn() {
 					tile.Button.Refresh()
 				}
 			})
-			tile.moveChan = nil
+			tile.selection = nil
 		}
 	}
 }
@@ -228,15 +375,8 @@ Takes `color` (string) which is the color of the user making the move on this cl
 and `assumeFirst` (bool) which allows us to optimize
 */
 func (self *ChessBoard) PrepareForMove(colorIsBlack bool, lastColorIsBlack bool) chan *Location {
-	moveChan := make(chan *Location)
-	innerMoveChan := make(chan *Location)
-
-	//concurrent thread to await a selection being returned, disable buttons, and pass it on
-	go func(moveChan chan *Location, innerMoveChan chan *Location) {
-		l := <-innerMoveChan
-		self.DisableAllBtn()
-		moveChan <- l
-	}(moveChan, innerMoveChan)
+	//a goroutine awaits a selection being returned, disables buttons, and passes it on
+	sel, moveChan := self.newSelection()
 
 	//logic to enable all pieces that are of the playing color
 	if colorIsBlack {
@@ -245,7 +385,7 @@ func (self *ChessBoard) PrepareForMove(colorIsBlack bool, lastColorIsBlack bool)
 			for _, tile := range rankSlice {
 				//check that the PieceType is white (we can play it) and defined
 				if tile.Piece.Black && tile.Piece.PieceType != "" {
-					tile.moveChan = &innerMoveChan
+					tile.selection = sel
 					tile.Button.Enable()
 					tile.Button.SetText("Move " + tile.Piece.PieceType)
 					tile.Button.Refresh()
@@ -267,7 +407,7 @@ func (self *ChessBoard) PrepareForMove(colorIsBlack bool, lastColorIsBlack bool)
 			for _, tile := range rankSlice {
 				//check that the PieceType is white (we can play it) and defined
 				if !tile.Piece.Black && tile.Piece.PieceType != "" {
-					tile.moveChan = &innerMoveChan
+					tile.selection = sel
 					tile.Button.Enable()
 					tile.Button.SetText("Move " + tile.Piece.PieceType)
 					tile.Button.Refresh()
@@ -295,34 +435,76 @@ A nil chan indicates that no adequate move was available and the selection proce
 be retried.
 */
 func (self *ChessBoard) MoveChooser(rank int, file int) chan *Location {
+	return self.chooseFrom(rank, file, self.moveTargets(rank, file))
+}
 
-	tile := self.Tiles[rank][file]
+/*
+PremoveChooser lays out the moves the piece could queue as a premove, like MoveChooser
+but before the opponent has moved. As their move can take a piece of ours out of the way
+or put one of theirs in reach, targets don't account for what is on the board; check the
+premove with LegalMove once their move has been made.
+*/
+func (self *ChessBoard) PremoveChooser(rank int, file int) chan *Location {
+	return self.chooseFrom(rank, file, self.premoveTargets(rank, file))
+}
 
-	moveChan := make(chan *Location)
-	innerMoveChan := make(chan *Location)
+// chooseFrom enables the tiles in targets, and the piece's own tile to cancel.
+func (self *ChessBoard) chooseFrom(rank int, file int, targets []*ChessTile) chan *Location {
 
-	//thread to wait a selection to be made, then disable all buttons and return it
-	go func(moveChan chan *Location, innerMoveChan chan *Location) {
-		l := <-innerMoveChan
-		fmt.Println(l, "move location from within move chooser")
-		self.DisableAllBtn()
-		moveChan <- l
-		fmt.Println("done moving chooser")
-	}(moveChan, innerMoveChan)
+	tile := self.Tiles[rank][file]
+
+	//a goroutine waits for a selection to be made, then disables all buttons and returns it
+	sel, moveChan := self.newSelection()
 
 	//cancel option
-	tile.moveChan = &innerMoveChan
+	tile.selection = sel
 	tile.Button.SetText("Cancel")
 	tile.Button.Enable()
 
+	for _, targetTile := range targets {
+		targetTile.selection = sel
+		targetTile.Button.SetText("Move here")
+		targetTile.Button.Enable()
+	}
+
+	if len(targets) > 0 {
+		return moveChan
+	} else {
+		return nil
+	}
+}
+
+/*
+LegalMove reports whether the player of the given color could make move on the
+board as it stands, by the same rules MoveChooser offers moves with.
+*/
+func (self *ChessBoard) LegalMove(move Move, colorIsBlack bool) bool {
+	piece := self.Tiles[move.From.Rank][move.From.File].Piece
+	if piece.PieceType == "" || piece.Black != colorIsBlack {
+		return false
+	}
+
+	target := self.Tiles[move.To.Rank][move.To.File]
+	for _, targetTile := range self.moveTargets(move.From.Rank, move.From.File) {
+		if targetTile == target {
+			return true
+		}
+	}
+	return false
+}
+
+// moveTargets finds the tiles the piece at rank and file can move to.
+func (self *ChessBoard) moveTargets(rank int, file int) []*ChessTile {
+
+	tile := self.Tiles[rank][file]
+
 	fmt.Println(tile.Piece.PieceType)
 	moveableSpots := 0
+	targets := make([]*ChessTile, 0)
 
-	enableMoveButton := func(desiredTile *ChessTile) {
+	addTarget := func(desiredTile *ChessTile) {
 		moveableSpots += 1
-		desiredTile.moveChan = &innerMoveChan
-		desiredTile.Button.SetText("Move here")
-		desiredTile.Button.Enable()
+		targets = append(targets, desiredTile)
 	}
 
 	searchList := func(locationsToLook *[8]Location) {
@@ -339,7 +521,7 @@ func (self *ChessBoard) MoveChooser(rank int, file int) chan *Location {
 			targetTile := self.Tiles[l.Rank][l.File]
 			if targetTile.Piece.PieceType == "" ||
 				targetTile.Piece.Black != self.Tiles[rank][file].Piece.Black {
-				enableMoveButton(targetTile)
+				addTarget(targetTile)
 			} else {
 				fmt.Println("Knight cannot take its own color at", l)
 			}
@@ -352,13 +534,13 @@ func (self *ChessBoard) MoveChooser(rank int, file int) chan *Location {
 			targetTile := self.Tiles[targetRank][file]
 			if targetTile.Piece.PieceType == "" {
 				fmt.Println(targetTile.Name, "is empty, opening and continuing search.")
-				enableMoveButton(targetTile)
+				addTarget(targetTile)
 			} else {
 				if targetTile.Piece.Black == self.Tiles[rank][file].Piece.Black {
 					fmt.Println(targetTile.Name, "Colors match, not opening and stopping search.")
 				} else {
 					fmt.Println(targetTile.Name, "Colors don't match, opening and stopping search.")
-					enableMoveButton(targetTile)
+					addTarget(targetTile)
 				}
 				break
 			}
@@ -369,13 +551,13 @@ func (self *ChessBoard) MoveChooser(rank int, file int) chan *Location {
 			targetTile := self.Tiles[targetRank][file]
 			if targetTile.Piece.PieceType == "" {
 				fmt.Println(targetTile.Name, "is empty, opening and continuing search.")
-				enableMoveButton(targetTile)
+				addTarget(targetTile)
 			} else {
 				if targetTile.Piece.Black == self.Tiles[rank][file].Piece.Black {
 					fmt.Println(targetTile.Name, "Colors match, not opening and stopping search.")
 				} else {
 					fmt.Println(targetTile.Name, "Colors don't match, opening and stopping search.")
-					enableMoveButton(targetTile)
+					addTarget(targetTile)
 				}
 				break
 			}
@@ -386,13 +568,13 @@ func (self *ChessBoard) MoveChooser(rank int, file int) chan *Location {
 			targetTile := self.Tiles[rank][targetFile]
 			if targetTile.Piece.PieceType == "" {
 				fmt.Println(targetTile.Name, "is empty, opening and continuing search.")
-				enableMoveButton(targetTile)
+				addTarget(targetTile)
 			} else {
 				if targetTile.Piece.Black == self.Tiles[rank][file].Piece.Black {
 					fmt.Println(targetTile.Name, "Colors match, not opening and stopping search.")
 				} else {
 					fmt.Println(targetTile.Name, "Colors don't match, opening and stopping search.")
-					enableMoveButton(targetTile)
+					addTarget(targetTile)
 				}
 				break
 			}
@@ -403,13 +585,13 @@ func (self *ChessBoard) MoveChooser(rank int, file int) chan *Location {
 			targetTile := self.Tiles[rank][targetFile]
 			if targetTile.Piece.PieceType == "" {
 				fmt.Println(targetTile.Name, "is empty, opening and continuing search.")
-				enableMoveButton(targetTile)
+				addTarget(targetTile)
 			} else {
 				if targetTile.Piece.Black == self.Tiles[rank][file].Piece.Black {
 					fmt.Println(targetTile.Name, "Colors match, not opening and stopping search.")
 				} else {
 					fmt.Println(targetTile.Name, "Colors don't match, opening and stopping search.")
-					enableMoveButton(targetTile)
+					addTarget(targetTile)
 				}
 				break
 			}
@@ -433,13 +615,13 @@ func (self *ChessBoard) MoveChooser(rank int, file int) chan *Location {
 
 			if targetTile.Piece.PieceType == "" {
 				fmt.Println(targetTile.Name, "is empty, opening and continuing search.")
-				enableMoveButton(targetTile)
+				addTarget(targetTile)
 			} else {
 				if targetTile.Piece.Black == self.Tiles[rank][file].Piece.Black {
 					fmt.Println(targetTile.Name, "Colors match, not opening and stopping search.")
 				} else {
 					fmt.Println(targetTile.Name, "Colors don't match, opening and stopping search.")
-					enableMoveButton(targetTile)
+					addTarget(targetTile)
 				}
 				break
 			}
@@ -462,13 +644,13 @@ func (self *ChessBoard) MoveChooser(rank int, file int) chan *Location {
 
 			if targetTile.Piece.PieceType == "" {
 				fmt.Println(targetTile.Name, "is empty, opening and continuing search.")
-				enableMoveButton(targetTile)
+				addTarget(targetTile)
 			} else {
 				if targetTile.Piece.Black == self.Tiles[rank][file].Piece.Black {
 					fmt.Println(targetTile.Name, "Colors match, not opening and stopping search.")
 				} else {
 					fmt.Println(targetTile.Name, "Colors don't match, opening and stopping search.")
-					enableMoveButton(targetTile)
+					addTarget(targetTile)
 				}
 				break
 			}
@@ -491,13 +673,13 @@ func (self *ChessBoard) MoveChooser(rank int, file int) chan *Location {
 
 			if targetTile.Piece.PieceType == "" {
 				fmt.Println(targetTile.Name, "is empty, opening and continuing search.")
-				enableMoveButton(targetTile)
+				addTarget(targetTile)
 			} else {
 				if targetTile.Piece.Black == self.Tiles[rank][file].Piece.Black {
 					fmt.Println(targetTile.Name, "Colors match, not opening and stopping search.")
 				} else {
 					fmt.Println(targetTile.Name, "Colors don't match, opening and stopping search.")
-					enableMoveButton(targetTile)
+					addTarget(targetTile)
 				}
 				break
 			}
@@ -519,13 +701,13 @@ func (self *ChessBoard) MoveChooser(rank int, file int) chan *Location {
 
 			if targetTile.Piece.PieceType == "" {
 				fmt.Println(targetTile.Name, "is empty, opening and continuing search.")
-				enableMoveButton(targetTile)
+				addTarget(targetTile)
 			} else {
 				if targetTile.Piece.Black == self.Tiles[rank][file].Piece.Black {
 					fmt.Println(targetTile.Name, "Colors match, not opening and stopping search.")
 				} else {
 					fmt.Println(targetTile.Name, "Colors don't match, opening and stopping search.")
-					enableMoveButton(targetTile)
+					addTarget(targetTile)
 				}
 				break
 			}
@@ -552,7 +734,7 @@ func (self *ChessBoard) MoveChooser(rank int, file int) chan *Location {
 
 		if fowardMoveTile.Piece.PieceType == "" {
 			fmt.Println(fowardMoveTile.Name)
-			enableMoveButton(fowardMoveTile)
+			addTarget(fowardMoveTile)
 		} else {
 			fmt.Println("Cannot move pawn foward as a", fowardMoveTile.Piece.PieceType, "is in the way.")
 		}
@@ -565,7 +747,7 @@ func (self *ChessBoard) MoveChooser(rank int, file int) chan *Location {
 				fmt.Println("Cannot take right with pawn as we cannot take our own piece.")
 			} else {
 				fmt.Println(rightTakeTile.Name)
-				enableMoveButton(rightTakeTile)
+				addTarget(rightTakeTile)
 			}
 		} else {
 			fmt.Println("Cannot take right as that is off the board")
@@ -579,7 +761,7 @@ func (self *ChessBoard) MoveChooser(rank int, file int) chan *Location {
 				fmt.Println("Cannot take right with pawn as we cannot take our own piece.")
 			} else {
 				fmt.Println(leftTakeTile.Name)
-				enableMoveButton(leftTakeTile)
+				addTarget(leftTakeTile)
 			}
 		}
 
@@ -595,7 +777,7 @@ func (self *ChessBoard) MoveChooser(rank int, file int) chan *Location {
 
 			if SecondFowardMoveTile.Piece.PieceType == "" {
 				fmt.Println(SecondFowardMoveTile.Name)
-				enableMoveButton(SecondFowardMoveTile)
+				addTarget(SecondFowardMoveTile)
 			} else {
 				fmt.Println("Cannot move pawn foward 2 as a", SecondFowardMoveTile.Piece.PieceType, "is in the way.")
 			}
@@ -640,11 +822,66 @@ func (self *ChessBoard) MoveChooser(rank int, file int) chan *Location {
 		searchList(locationsToLook)
 	}
 
-	if moveableSpots > 0 {
-		return moveChan
-	} else {
-		return nil
+	return targets
+}
+
+/*
+premoveTargets lists every square the piece at rank, file could reach on an empty board.
+Pawns get their diagonals whether or not there is anything to take.
+*/
+func (self *ChessBoard) premoveTargets(rank int, file int) []*ChessTile {
+	piece := self.Tiles[rank][file].Piece
+	targets := make([]*ChessTile, 0)
+
+	onBoard := func(targetRank int, targetFile int) bool {
+		return targetRank >= 0 && targetRank < 8 && targetFile >= 0 && targetFile < 8
+	}
+	addTarget := func(targetRank int, targetFile int) {
+		if onBoard(targetRank, targetFile) {
+			targets = append(targets, self.Tiles[targetRank][targetFile])
+		}
+	}
+	//sliding pieces run to the edge of the board, through anything in the way
+	slide := func(directions [4][2]int) {
+		for _, d := range directions {
+			for targetRank, targetFile := rank+d[0], file+d[1]; onBoard(targetRank, targetFile); targetRank, targetFile = targetRank+d[0], targetFile+d[1] {
+				addTarget(targetRank, targetFile)
+			}
+		}
+	}
+	straight := [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	diagonal := [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+
+	switch piece.PieceType {
+	case "pawn":
+		forward, home := 1, 1
+		if piece.Black {
+			forward, home = -1, 6
+		}
+		addTarget(rank+forward, file)
+		addTarget(rank+forward, file+1)
+		addTarget(rank+forward, file-1)
+		if rank == home {
+			addTarget(rank+2*forward, file)
+		}
+	case "rook":
+		slide(straight)
+	case "knight":
+		for _, d := range [8][2]int{{2, 1}, {1, 2}, {-2, 1}, {-1, 2}, {-2, -1}, {-1, -2}, {2, -1}, {1, -2}} {
+			addTarget(rank+d[0], file+d[1])
+		}
+	case "bishop":
+		slide(diagonal)
+	case "queen":
+		slide(straight)
+		slide(diagonal)
+	case "king":
+		for _, d := range append(straight[:], diagonal[:]...) {
+			addTarget(rank+d[0], file+d[1])
+		}
 	}
+
+	return targets
 }
 
 func (self *ChessBoard) MovePiece(from *Location, to *Location, reverse bool) bool {
@@ -704,7 +941,7 @@ func (self *ChessBoard) MovePiece(from *Location, to *Location, reverse bool) bo
 }
 
 func NewChessBoard() *ChessBoard {
-	board := ChessBoard{}
+	board := ChessBoard{closed: make(chan struct{})}
 	uiTiles := make([]fyne.CanvasObject, 64)
 
 	iter := 0
@@ -712,6 +949,11 @@ func NewChessBoard() *ChessBoard {
 	for rank := 7; rank >= 0; rank-- {
 		for file := 0; file < 8; file++ {
 			tile := initChessTileAtPos(rank, file)
+			tile.rightClick.onTapped = func() {
+				if board.OnSecondaryTap != nil {
+					board.OnSecondaryTap()
+				}
+			}
 			board.Tiles[rank][file] = tile
 			uiTiles[iter] = tile.UiEL
 			iter++
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+const defaultServerUrl = "http://66.91.172.196:5000"
+
+// overrideProfileName is the name of the unsaved profile made from the -server flag or CHESS_SERVER.
+const overrideProfileName = "Command line"
+
+type serverProfile struct {
+	Name         string `json:"name"`
+	URL          string `json:"url"`
+	LastUsername string `json:"last_username"`
+}
+
+type clientConfig struct {
+	Selected string          `json:"selected"`
+	Profiles []serverProfile `json:"profiles"`
+
+	path string
+	//directory to record server traffic into, from the -record flag or CHESS_RECORD
+	recordDir string
+	//selection from the file, kept so a command line override is not saved over it
+	savedSelected string
+}
+
+/*
+configPath works out where the config file lives. In order of priority it is
+the -config flag, the CHESS_CONFIG environment variable, or chess-fe-go/config.json
+in the user config directory.
+*/
+func configPath(flagPath string) (string, error) {
+	if flagPath != "" {
+		return flagPath, nil
+	}
+	if envPath := os.Getenv("CHESS_CONFIG"); envPath != "" {
+		return envPath, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "chess-fe-go", "config.json"), nil
+}
+
+/*
+loadConfig reads the config file, falling back to a single default profile
+if there is none yet. A server given by the -server flag or CHESS_SERVER
+environment variable is added as an unsaved profile and selected.
+*/
+func loadConfig() *clientConfig {
+	serverFlag := flag.String("server", "", "url of the chess server, overrides the saved profile")
+	configFlag := flag.String("config", "", "path to the config file")
+	recordFlag := flag.String("record", "", "directory to record server requests and responses into, as test fixtures")
+	flag.Parse()
+
+	config := &clientConfig{recordDir: *recordFlag}
+	if config.recordDir == "" {
+		config.recordDir = os.Getenv("CHESS_RECORD")
+	}
+
+	path, err := configPath(*configFlag)
+	if err != nil {
+		fmt.Println("Error finding config directory:", err)
+	} else {
+		config.path = path
+		data, err := os.ReadFile(path)
+		if err == nil {
+			if err := json.Unmarshal(data, config); err != nil {
+				fmt.Println("Error parsing config file:", err)
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Println("Error reading config file:", err)
+		}
+	}
+
+	config.savedSelected = config.Selected
+
+	if len(config.Profiles) == 0 {
+		config.Profiles = []serverProfile{{Name: "Default", URL: defaultServerUrl}}
+	}
+
+	overrideUrl := *serverFlag
+	if overrideUrl == "" {
+		overrideUrl = os.Getenv("CHESS_SERVER")
+	}
+	if overrideUrl != "" {
+		config.Profiles = append(config.Profiles, serverProfile{Name: overrideProfileName, URL: overrideUrl})
+		config.Selected = overrideProfileName
+	}
+
+	if config.profile(config.Selected) == nil {
+		config.Selected = config.Profiles[0].Name
+	}
+
+	return config
+}
+
+// profile finds a profile by name, or nil if there is none.
+func (self *clientConfig) profile(name string) *serverProfile {
+	for i := range self.Profiles {
+		if self.Profiles[i].Name == name {
+			return &self.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// profileNames lists the profiles in the order they were added, for the server picker.
+func (self *clientConfig) profileNames() []string {
+	names := make([]string, len(self.Profiles))
+	for i, p := range self.Profiles {
+		names[i] = p.Name
+	}
+	return names
+}
+
+/*
+gameDataDir is where things kept about a user's games on a server are stored,
+such as conditional moves. Game ids are only unique on one server, so each
+server and user gets a directory of their own. Empty if there is no config file location.
+*/
+func (self *clientConfig) gameDataDir(serverURL string, username string) string {
+	if self.path == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(self.path), "games", url.QueryEscape(serverURL), url.QueryEscape(username))
+}
+
+// save writes the config file, leaving out the command line profile.
+func (self *clientConfig) save() error {
+	if self.path == "" {
+		return errors.New("no config file location")
+	}
+
+	toSave := clientConfig{Selected: self.Selected}
+	for _, p := range self.Profiles {
+		if p.Name != overrideProfileName {
+			toSave.Profiles = append(toSave.Profiles, p)
+		}
+	}
+	if toSave.Selected == overrideProfileName {
+		toSave.Selected = self.savedSelected
+	}
+
+	data, err := json.MarshalIndent(toSave, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(self.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(self.path, data, 0o600)
+}
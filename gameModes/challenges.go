@@ -0,0 +1,288 @@
+package gameModes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/jjj333-p/chess-fe-go/api"
+	"time"
+)
+
+// challengeRefreshInterval is how often the inbox checks for new and answered challenges.
+const challengeRefreshInterval = 5 * time.Second
+
+// timeControlPresets are the time controls a challenge can be sent with, in menu order.
+var timeControlPresets = []api.TimeControl{
+	{},
+	{Base: 3 * 60, Increment: 2},
+	{Base: 5 * 60},
+	{Base: 5 * 60, Delay: 3},
+	{Base: 10 * 60, Increment: 5},
+	{Base: 15 * 60, Increment: 10},
+	{Base: 30 * 60},
+}
+
+var colourNames = map[string]string{
+	api.ColourWhite:  "White",
+	api.ColourBlack:  "Black",
+	api.ColourRandom: "Random",
+}
+
+// describeChallenge sums up a challenge's options from the point of view of whoever is reading it.
+func describeChallenge(ch *api.Challenge, incoming bool) string {
+	colour := "random colours"
+	switch {
+	case ch.Colour == api.ColourRandom:
+	case incoming == (ch.Colour == api.ColourWhite):
+		colour = "you play Black"
+	default:
+		colour = "you play White"
+	}
+
+	rated := "casual"
+	if ch.Rated {
+		rated = "rated"
+	}
+
+	return fmt.Sprintf("%s, %s, %s", colour, ch.TimeControl, rated)
+}
+
+/*
+showChallengeForm asks for the colour, time control and rating of a challenge
+to opponent, then sends it. onSent is called on the ui thread once the server
+has it. Servers without challenges get a game started straight away instead,
+which onPlay is called with. Sending gives up when ctx is cancelled.
+*/
+func showChallengeForm(ctx context.Context, client *api.Client, opponent api.DbUser, w fyne.Window, onSent func(*api.Challenge), onPlay func(*api.DbGame)) {
+	colourRadio := widget.NewRadioGroup([]string{"White", "Black", "Random"}, nil)
+	colourRadio.Horizontal = true
+	colourRadio.Required = true
+	colourRadio.SetSelected("Random")
+
+	timeNames := make([]string, len(timeControlPresets))
+	for i, tc := range timeControlPresets {
+		timeNames[i] = tc.String()
+	}
+	timeSelect := widget.NewSelect(timeNames, nil)
+	timeSelect.SetSelectedIndex(0)
+
+	ratedCheck := widget.NewCheck("Rated", nil)
+	ratedCheck.SetChecked(true)
+
+	dialog.ShowForm("Challenge "+opponent.Username, "Send", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Play as", colourRadio),
+		widget.NewFormItem("Time", timeSelect),
+		widget.NewFormItem("", ratedCheck),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+
+		req := api.ChallengeRequest{
+			ToID:        opponent.UID,
+			Colour:      api.ColourRandom,
+			TimeControl: timeControlPresets[timeSelect.SelectedIndex()],
+			Rated:       ratedCheck.Checked,
+		}
+		for colour, name := range colourNames {
+			if name == colourRadio.Selected {
+				req.Colour = colour
+			}
+		}
+
+		go func() {
+			ch, err := client.SendChallenge(ctx, req)
+			if errors.Is(err, api.ErrNotFound) {
+				//the server only knows the old way, where the game starts without the opponent's say
+				fmt.Println("Server has no challenges, starting the game directly")
+				game, err := client.NewGame(ctx, opponent.UID)
+				fyne.Do(func() {
+					if ctx.Err() != nil {
+						return
+					}
+					if err != nil {
+						dialog.ShowError(fmt.Errorf("error creating game: %v", err), w)
+						return
+					}
+					onPlay(game)
+				})
+				return
+			}
+			fyne.Do(func() {
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("error sending challenge: %v", err), w)
+					return
+				}
+				onSent(ch)
+			})
+		}()
+	}, w)
+}
+
+/*
+challengeInbox lists the pending challenges to and from the user, with buttons
+to answer or cancel them, and keeps it up to date until ctx is cancelled.
+onPlay is called on the ui thread with the new game when a challenge is accepted,
+whether by us or by the opponent. The challenges we sent that answeredElsewhere
+reports, like rematches, are listed but their answers are left to whatever sent them.
+The returned func refreshes the list straight away.
+*/
+func challengeInbox(ctx context.Context, client *api.Client, account AccountData, w fyne.Window, onPlay func(*api.DbGame), answeredElsewhere func(challengeID int) bool) (fyne.CanvasObject, func()) {
+	inbox := container.NewVBox(widget.NewLabel("Loading challenges..."))
+
+	//last state seen of each challenge we sent, to notice when they are answered
+	sentStates := make(map[int]string)
+
+	answered := func(ch api.Challenge) {
+		switch ch.State {
+		case api.ChallengeAccepted:
+			dialog.ShowConfirm("Challenge accepted",
+				ch.ToName+" accepted your challenge. Play the game now?",
+				func(play bool) {
+					if !play {
+						return
+					}
+					go func() {
+						game, err := client.GetGame(ctx, ch.GameID)
+						fyne.Do(func() {
+							if err != nil {
+								dialog.ShowError(err, w)
+								return
+							}
+							onPlay(game)
+						})
+					}()
+				}, w)
+		case api.ChallengeDeclined:
+			dialog.ShowInformation("Challenge declined", ch.ToName+" declined your challenge.", w)
+		case api.ChallengeExpired:
+			dialog.ShowInformation("Challenge expired", "Your challenge to "+ch.ToName+" expired without an answer.", w)
+		}
+	}
+
+	var refresh func()
+
+	//runs the api call off the ui thread, then refreshes the list or shows the error
+	respond := func(call func() error) {
+		go func() {
+			err := call()
+			fyne.Do(func() {
+				if err != nil && ctx.Err() == nil {
+					dialog.ShowError(err, w)
+				}
+				refresh()
+			})
+		}()
+	}
+
+	show := func(challenges []api.Challenge) {
+		inbox.RemoveAll()
+		now := time.Now()
+
+		for _, ch := range challenges {
+			outgoing := ch.FromName == account.Cred.Username
+			if outgoing {
+				if previous, seen := sentStates[ch.ChallengeID]; seen && previous == api.ChallengePending && ch.State != api.ChallengePending &&
+					!answeredElsewhere(ch.ChallengeID) {
+					answered(ch)
+				}
+				sentStates[ch.ChallengeID] = ch.State
+			}
+
+			if ch.State != api.ChallengePending || ch.Expired(now) {
+				continue
+			}
+
+			expiry := ""
+			if expiresAt, err := time.Parse(time.RFC3339, ch.ExpiresAt); err == nil {
+				expiry = fmt.Sprintf(" (expires in %s)", time.Until(expiresAt).Truncate(time.Second))
+			}
+
+			var row *fyne.Container
+			if outgoing {
+				row = container.NewHBox(
+					widget.NewLabel(fmt.Sprintf("You challenged %s: %s%s", ch.ToName, describeChallenge(&ch, false), expiry)),
+					layout.NewSpacer(),
+					widget.NewButton("Cancel", func() {
+						respond(func() error { return client.CancelChallenge(ctx, ch.ChallengeID) })
+					}),
+				)
+			} else {
+				row = container.NewHBox(
+					widget.NewLabel(fmt.Sprintf("%s challenges you: %s%s", ch.FromName, describeChallenge(&ch, true), expiry)),
+					layout.NewSpacer(),
+					widget.NewButton("Accept", func() {
+						go func() {
+							game, err := client.AcceptChallenge(ctx, ch.ChallengeID)
+							fyne.Do(func() {
+								if err != nil {
+									if ctx.Err() == nil {
+										dialog.ShowError(err, w)
+									}
+									refresh()
+									return
+								}
+								onPlay(game)
+							})
+						}()
+					}),
+					widget.NewButton("Decline", func() {
+						respond(func() error { return client.DeclineChallenge(ctx, ch.ChallengeID) })
+					}),
+				)
+			}
+			inbox.Add(row)
+		}
+
+		if len(inbox.Objects) == 0 {
+			inbox.Add(widget.NewLabel("No challenges"))
+		}
+	}
+
+	refresh = func() {
+		go func() {
+			challenges, err := client.Challenges(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			fyne.Do(func() {
+				if ctx.Err() != nil {
+					return
+				}
+				if errors.Is(err, api.ErrNotFound) {
+					inbox.RemoveAll()
+					inbox.Add(widget.NewLabel("This server has no challenges. Games start as soon as you pick an opponent."))
+					return
+				}
+				if err != nil {
+					fmt.Println("Error fetching challenges:", err)
+					inbox.RemoveAll()
+					inbox.Add(widget.NewLabel("Could not load challenges: " + err.Error()))
+					return
+				}
+				show(challenges)
+			})
+		}()
+	}
+
+	go func() {
+		for {
+			refresh()
+			select {
+			case <-time.After(challengeRefreshInterval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return inbox, refresh
+}
@@ -0,0 +1,240 @@
+package gameModes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/jjj333-p/chess-fe-go/api"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The user can send chatBurst messages in any chatWindow, so they are told to slow down before the server refuses them.
+const (
+	chatBurst  = 5
+	chatWindow = 10 * time.Second
+)
+
+// chatLimiter keeps track of recently sent messages to limit how often more can be sent.
+type chatLimiter struct {
+	sent []time.Time
+}
+
+// allow reports whether a message can be sent at now, and if so counts it as sent.
+func (self *chatLimiter) allow(now time.Time) bool {
+	recent := self.sent[:0]
+	for _, sent := range self.sent {
+		if now.Sub(sent) < chatWindow {
+			recent = append(recent, sent)
+		}
+	}
+	self.sent = recent
+
+	if len(self.sent) >= chatBurst {
+		return false
+	}
+	self.sent = append(self.sent, now)
+	return true
+}
+
+/*
+chatPanel is the chat sidebar of a game. It loads the history when created,
+and new messages are passed to Add as they arrive with the game's events,
+which can be before the history has loaded.
+Muting hides everyone else's messages until it is turned off again.
+Everything but newChatPanel must be called on the ui thread.
+*/
+type chatPanel struct {
+	Container *fyne.Container
+
+	ctx      context.Context
+	client   *api.Client
+	gameID   int
+	username string
+
+	messages []api.ChatMessage
+	seen     map[int]bool
+	muted    bool
+	limiter  chatLimiter
+
+	list   *fyne.Container
+	empty  *widget.Label //shown in the list while it has no messages
+	scroll *container.Scroll
+	notice *widget.Label
+	entry  *widget.Entry
+}
+
+// newChatPanel builds the chat for a game. A read only panel shows the messages without a way to send any.
+func newChatPanel(ctx context.Context, client *api.Client, gameID int, username string, readOnly bool) *chatPanel {
+	self := &chatPanel{
+		ctx:      ctx,
+		client:   client,
+		gameID:   gameID,
+		username: username,
+		seen:     make(map[int]bool),
+		list:     container.NewVBox(),
+		empty:    widget.NewLabel("No messages yet"),
+		notice:   widget.NewLabel(""),
+		entry:    widget.NewEntry(),
+	}
+	self.notice.Wrapping = fyne.TextWrapWord
+	self.notice.Hide()
+
+	self.scroll = container.NewVScroll(self.list)
+	self.scroll.SetMinSize(fyne.NewSize(220, 300))
+
+	muteCheck := widget.NewCheck("Mute", func(muted bool) {
+		self.muted = muted
+		self.render()
+	})
+	header := container.NewHBox(widget.NewLabel("Chat"), layout.NewSpacer(), muteCheck)
+
+	var footer fyne.CanvasObject = self.notice
+	if !readOnly {
+		self.entry.SetPlaceHolder("Say something...")
+		self.entry.OnSubmitted = func(string) { self.send() }
+		sendBtn := widget.NewButtonWithIcon("", theme.MailSendIcon(), self.send)
+		footer = container.NewVBox(self.notice, container.NewBorder(nil, nil, nil, sendBtn, self.entry))
+	}
+
+	self.Container = container.NewBorder(header, footer, nil, nil, self.scroll)
+	self.render()
+
+	go func() {
+		messages, err := client.ChatHistory(ctx, gameID, 0)
+		if ctx.Err() != nil {
+			return
+		}
+		fyne.Do(func() {
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, api.ErrNotFound) {
+				self.setNotice("Chat is not available on this server.")
+				self.entry.Disable()
+				return
+			}
+			if err != nil {
+				fmt.Println("Error fetching chat:", err)
+				self.setNotice("Could not load the chat: " + err.Error())
+				return
+			}
+			for _, message := range messages {
+				self.Add(message)
+			}
+		})
+	}()
+
+	return self
+}
+
+/*
+Add shows a message, unless it has been shown already. Messages are kept in
+the order of their ids, as the history can arrive after newer messages.
+*/
+func (self *chatPanel) Add(message api.ChatMessage) {
+	if self.seen[message.MessageID] {
+		return
+	}
+	self.seen[message.MessageID] = true
+
+	at := len(self.messages)
+	for at > 0 && self.messages[at-1].MessageID > message.MessageID {
+		at--
+	}
+	self.messages = slices.Insert(self.messages, at, message)
+
+	//anything but a new last message means laying the list out again
+	if at < len(self.messages)-1 {
+		self.render()
+		return
+	}
+	if self.shown(message) {
+		self.list.Remove(self.empty)
+		self.list.Add(chatLine(message))
+		self.scroll.ScrollToBottom()
+	}
+}
+
+func (self *chatPanel) shown(message api.ChatMessage) bool {
+	return !self.muted || message.Username == self.username
+}
+
+// render rebuilds the list of messages, for when muting changes what is shown.
+func (self *chatPanel) render() {
+	self.list.RemoveAll()
+	for _, message := range self.messages {
+		if self.shown(message) {
+			self.list.Add(chatLine(message))
+		}
+	}
+	if len(self.list.Objects) == 0 {
+		self.list.Add(self.empty)
+	}
+	self.scroll.ScrollToBottom()
+}
+
+func (self *chatPanel) setNotice(notice string) {
+	self.notice.SetText(notice)
+	if notice == "" {
+		self.notice.Hide()
+	} else {
+		self.notice.Show()
+	}
+}
+
+// send posts what has been typed, keeping it in the entry if it can't be sent.
+func (self *chatPanel) send() {
+	text := strings.TrimSpace(self.entry.Text)
+	if text == "" {
+		return
+	}
+	if len(text) > api.MaxChatLength {
+		self.setNotice(fmt.Sprintf("Messages can be at most %d characters.", api.MaxChatLength))
+		return
+	}
+	if !self.limiter.allow(time.Now()) {
+		self.setNotice("You are sending messages too quickly, wait a moment.")
+		return
+	}
+
+	self.setNotice("")
+	self.entry.SetText("")
+	go func() {
+		message, err := self.client.SendChat(self.ctx, self.gameID, text)
+		if self.ctx.Err() != nil {
+			return
+		}
+		fyne.Do(func() {
+			if err != nil {
+				if self.entry.Text == "" {
+					self.entry.SetText(text)
+				}
+				if errors.Is(err, api.ErrRateLimited) {
+					self.setNotice("You are sending messages too quickly, wait a moment.")
+				} else {
+					self.setNotice("Could not send: " + err.Error())
+				}
+				return
+			}
+			self.Add(*message)
+		})
+	}()
+}
+
+// chatLine is how one message is shown in the list.
+func chatLine(message api.ChatMessage) fyne.CanvasObject {
+	text := message.Username + ": " + message.Text
+	if sentAt, err := time.Parse(time.RFC3339, message.SentAt); err == nil {
+		text = sentAt.Local().Format("15:04") + " " + text
+	}
+	line := widget.NewLabel(text)
+	line.Wrapping = fyne.TextWrapWord
+	return line
+}
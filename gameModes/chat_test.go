@@ -0,0 +1,36 @@
+package gameModes
+
+import (
+	"context"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/jjj333-p/chess-fe-go/api"
+)
+
+func TestChatPanelOrder(t *testing.T) {
+	test.NewApp()
+	ctx, cancel := context.WithCancel(context.Background())
+	//cancelled so the panel doesn't load any history itself
+	cancel()
+	chat := newChatPanel(ctx, api.NewClient("http://localhost:1"), 1, "white", false)
+
+	//live messages come in before the history that came before them, which includes one of them again
+	for _, id := range []int{4, 5, 1, 2, 3, 4} {
+		chat.Add(api.ChatMessage{MessageID: id, Username: "black", Text: "hi"})
+	}
+
+	var ids []int
+	for _, message := range chat.messages {
+		ids = append(ids, message.MessageID)
+	}
+	if len(ids) != 5 || len(chat.list.Objects) != 5 {
+		t.Fatalf("messages %v, %d lines shown", ids, len(chat.list.Objects))
+	}
+	for i, id := range ids {
+		if id != i+1 {
+			t.Errorf("messages in the order %v", ids)
+			break
+		}
+	}
+}
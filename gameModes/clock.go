@@ -0,0 +1,212 @@
+package gameModes
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"github.com/jjj333-p/chess-fe-go/api"
+	"time"
+)
+
+// lowTimeWarning is how little time left turns a clock red.
+const lowTimeWarning = 20 * time.Second
+
+// clockTick is how often the clocks are redrawn.
+const clockTick = 100 * time.Millisecond
+
+/*
+chessClock shows both players' time and counts down whoever is to move. It
+keeps time by itself between moves, and online games also Sync it from the
+server's clocks. It is hidden for untimed games.
+Everything but newChessClock must be called on the ui thread.
+*/
+type chessClock struct {
+	Container *fyne.Container
+
+	timeControl api.TimeControl
+	//white's and black's time left as of turnStarted
+	remaining   [2]time.Duration
+	turnStarted time.Time
+	//index of the clock that is running, or -1 if neither is
+	running int
+	flagged bool
+	//server time of the last reading synced from, to ignore ones arriving out of order
+	syncedAt time.Time
+
+	texts  [2]*canvas.Text
+	onFlag func(black bool)
+}
+
+/*
+newChessClock makes a clock for timeControl that redraws itself until ctx is
+cancelled. Neither side's clock runs until the first Press. onFlag is called
+on the ui thread when the player to move runs out of time.
+*/
+func newChessClock(ctx context.Context, timeControl api.TimeControl, onFlag func(black bool)) *chessClock {
+	self := &chessClock{
+		running: -1,
+		onFlag:  onFlag,
+	}
+	for i := range self.texts {
+		self.texts[i] = canvas.NewText("", theme.Color(theme.ColorNameForeground))
+		self.texts[i].TextSize = 20
+		self.texts[i].TextStyle.Monospace = true
+	}
+	self.Container = container.NewHBox(self.texts[0], layout.NewSpacer(), self.texts[1])
+	self.SetTimeControl(timeControl)
+
+	go func() {
+		ticker := time.NewTicker(clockTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fyne.Do(self.tick)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return self
+}
+
+// SetTimeControl stops the clock and resets both sides to the base time.
+func (self *chessClock) SetTimeControl(timeControl api.TimeControl) {
+	self.timeControl = timeControl
+	base := time.Duration(timeControl.Base) * time.Second
+	self.remaining = [2]time.Duration{base, base}
+	self.running = -1
+	self.flagged = false
+
+	if timeControl.Base == 0 {
+		self.Container.Hide()
+	} else {
+		self.Container.Show()
+	}
+	self.draw()
+}
+
+// left is how much time side has at now.
+func (self *chessClock) left(side int, now time.Time) time.Duration {
+	left := self.remaining[side]
+	if side == self.running {
+		left -= now.Sub(self.turnStarted)
+	}
+	return max(left, 0)
+}
+
+/*
+Press is called after a move, with the side now to move. It ends the other
+side's turn, giving back time as the time control says, and starts the clock
+of the side to move. The first press starts the clocks.
+*/
+func (self *chessClock) Press(blackToMove bool) {
+	if self.timeControl.Base == 0 || self.flagged {
+		return
+	}
+	next := 0
+	if blackToMove {
+		next = 1
+	}
+	if self.running == next {
+		return
+	}
+
+	now := time.Now()
+	if self.running >= 0 {
+		used := now.Sub(self.turnStarted)
+		self.remaining[self.running] = self.left(self.running, now) + self.timeControl.Credit(used)
+	}
+	self.running = next
+	self.turnStarted = now
+	self.draw()
+}
+
+// Stop freezes both clocks where they are.
+func (self *chessClock) Stop() {
+	if self.running >= 0 {
+		self.remaining[self.running] = self.left(self.running, time.Now())
+		self.running = -1
+	}
+	self.draw()
+}
+
+/*
+Sync sets the clocks from the server, unless a later reading has been synced already.
+A reading without the server's time can't be put in order, so it is always used,
+corrected for the round trip it took.
+*/
+func (self *chessClock) Sync(reading *api.ClockReading) {
+	if serverTime := reading.ServerTimestamp(); !serverTime.IsZero() {
+		if serverTime.Before(self.syncedAt) {
+			return
+		}
+		self.syncedAt = serverTime
+	}
+
+	now := time.Now()
+	white, black := reading.Remaining(now)
+	self.remaining = [2]time.Duration{white, black}
+	self.turnStarted = now
+	switch reading.Running {
+	case "W":
+		self.running = 0
+	case "B":
+		self.running = 1
+	default:
+		self.running = -1
+	}
+	//the server has the final say on whether time ran out
+	self.flagged = false
+	self.draw()
+}
+
+func (self *chessClock) tick() {
+	if self.running < 0 || self.flagged {
+		return
+	}
+	if self.left(self.running, time.Now()) == 0 {
+		self.flagged = true
+		black := self.running == 1
+		self.Stop()
+		if self.onFlag != nil {
+			self.onFlag(black)
+		}
+		return
+	}
+	self.draw()
+}
+
+func (self *chessClock) draw() {
+	now := time.Now()
+	for side, text := range self.texts {
+		left := self.left(side, now)
+
+		name := "White"
+		if side == 1 {
+			name = "Black"
+		}
+		text.Text = name + " " + formatClock(left)
+		text.TextStyle.Bold = side == self.running
+		if left < lowTimeWarning && self.timeControl.Base > 0 {
+			text.Color = theme.Color(theme.ColorNameError)
+		} else {
+			text.Color = theme.Color(theme.ColorNameForeground)
+		}
+		text.Refresh()
+	}
+}
+
+// formatClock shows time left as minutes and seconds, with tenths once it is under ten seconds.
+func formatClock(left time.Duration) string {
+	if left < 10*time.Second {
+		return fmt.Sprintf("0:%04.1f", left.Truncate(100*time.Millisecond).Seconds())
+	}
+	left = left.Truncate(time.Second)
+	return fmt.Sprintf("%d:%02d", int(left.Minutes()), int(left.Seconds())%60)
+}
@@ -0,0 +1,49 @@
+package gameModes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jjj333-p/chess-fe-go/api"
+)
+
+// reading is of stopped clocks, white's at whiteMs and black's at a minute, as the server read them at serverTime.
+func reading(whiteMs int64, serverTime string) *api.ClockReading {
+	return &api.ClockReading{
+		ClockState: api.ClockState{WhiteMs: whiteMs, BlackMs: 60000, ServerTime: serverTime},
+		ReceivedAt: time.Now(),
+	}
+}
+
+func TestClockSyncOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	clock := newChessClock(ctx, api.TimeControl{Base: 60}, nil)
+
+	white := func() time.Duration {
+		return clock.left(0, time.Now())
+	}
+
+	clock.Sync(reading(50000, "2026-10-18T12:00:02Z"))
+	if white() != 50*time.Second {
+		t.Fatalf("white has %v after the first reading", white())
+	}
+
+	//an older reading arriving late is ignored
+	clock.Sync(reading(55000, "2026-10-18T12:00:01Z"))
+	if white() != 50*time.Second {
+		t.Errorf("white has %v after an older reading", white())
+	}
+
+	//without the server's time there is no telling, so the reading is used
+	clock.Sync(reading(40000, ""))
+	if white() != 40*time.Second {
+		t.Errorf("white has %v after a reading without server time", white())
+	}
+
+	clock.Sync(reading(30000, "2026-10-18T12:00:03Z"))
+	if white() != 30*time.Second {
+		t.Errorf("white has %v after a later reading", white())
+	}
+}
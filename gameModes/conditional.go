@@ -0,0 +1,410 @@
+package gameModes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/jjj333-p/chess-fe-go/chessboard"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// conditionalStep is one branch point of a conditional line: if they play Theirs, we reply Ours.
+type conditionalStep struct {
+	Theirs chessboard.Move `json:"theirs"`
+	Ours   chessboard.Move `json:"ours"`
+}
+
+/*
+conditionalMoves are the replies prepared for a correspondence game, as lines
+of steps followed one after the other. Lines starting with the same moves
+share those replies, so together they form a tree. They are saved per game so
+they are still there when the game is next opened.
+*/
+type conditionalMoves struct {
+	// Ply is how many moves had been played when the lines start.
+	Ply   int                 `json:"ply"`
+	Lines [][]conditionalStep `json:"lines"`
+
+	mu   sync.Mutex
+	path string
+}
+
+// squareName gives a location in algebraic notation, like e4.
+func squareName(l *chessboard.Location) string {
+	return string(rune('a'+l.File)) + strconv.Itoa(l.Rank+1)
+}
+
+func moveName(m chessboard.Move) string {
+	return squareName(m.From) + "-" + squareName(m.To)
+}
+
+/*
+loadConditionalMoves reads the conditional moves saved for a game. With no
+dataDir they are only kept for as long as the game is open.
+*/
+func loadConditionalMoves(dataDir string, gameID int) *conditionalMoves {
+	conditionals := &conditionalMoves{}
+	if dataDir == "" {
+		return conditionals
+	}
+	conditionals.path = filepath.Join(dataDir, fmt.Sprintf("conditional_%d.json", gameID))
+
+	data, err := os.ReadFile(conditionals.path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Println("Error reading conditional moves:", err)
+		}
+		return conditionals
+	}
+	if err := json.Unmarshal(data, conditionals); err != nil {
+		fmt.Println("Error parsing conditional moves:", err)
+	}
+	return conditionals
+}
+
+// save writes the lines to disk, removing the file once there are none. The lock must be held.
+func (self *conditionalMoves) save() {
+	if self.path == "" {
+		return
+	}
+
+	if len(self.Lines) == 0 {
+		if err := os.Remove(self.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fmt.Println("Error removing conditional moves:", err)
+		}
+		return
+	}
+
+	data, err := json.MarshalIndent(self, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(self.path), 0o700)
+	}
+	if err == nil {
+		err = os.WriteFile(self.path, data, 0o600)
+	}
+	if err != nil {
+		fmt.Println("Error saving conditional moves:", err)
+	}
+}
+
+/*
+add saves a new line starting after the first ply moves. Lines prepared for an
+earlier position are dropped. It is refused if it replies differently from a
+line already saved to the same moves.
+*/
+func (self *conditionalMoves) add(ply int, line []conditionalStep) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if ply != self.Ply {
+		self.Ply = ply
+		self.Lines = nil
+	}
+
+	for _, existing := range self.Lines {
+		for i := 0; i < len(existing) && i < len(line); i++ {
+			if !sameMove(existing[i].Theirs, line[i].Theirs) {
+				break
+			}
+			if !sameMove(existing[i].Ours, line[i].Ours) {
+				return fmt.Errorf("after %s you already reply %s in another line", moveName(line[i].Theirs), moveName(existing[i].Ours))
+			}
+		}
+	}
+
+	self.Lines = append(self.Lines, line)
+	self.save()
+	return nil
+}
+
+func (self *conditionalMoves) remove(i int) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if i < len(self.Lines) {
+		self.Lines = append(self.Lines[:i], self.Lines[i+1:]...)
+		self.save()
+	}
+}
+
+// lines returns a copy of the saved lines and the ply they start after.
+func (self *conditionalMoves) lines() (int, [][]conditionalStep) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.Ply, append([][]conditionalStep(nil), self.Lines...)
+}
+
+/*
+reply looks for the prepared answer to the opponent playing theirs after the
+first ply moves, or nil if there is none. Nothing changes until the move we
+actually make is passed to played.
+*/
+func (self *conditionalMoves) reply(ply int, theirs chessboard.Move) *chessboard.Move {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if ply != self.Ply {
+		return nil
+	}
+	for _, line := range self.Lines {
+		if sameMove(line[0].Theirs, theirs) {
+			ours := line[0].Ours
+			return &ours
+		}
+	}
+	return nil
+}
+
+/*
+played is called once our move ours has been sent in reply to theirs, which
+was played after the first ply moves. Lines that expected both go on past them,
+and the rest are dropped, as they are for a position that wasn't reached.
+*/
+func (self *conditionalMoves) played(ply int, theirs chessboard.Move, ours chessboard.Move) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if len(self.Lines) == 0 {
+		return
+	}
+	if ply != self.Ply {
+		fmt.Println("conditional moves were for move", self.Ply, "not", ply, "dropping them")
+		self.Lines = nil
+		self.save()
+		return
+	}
+
+	remaining := make([][]conditionalStep, 0)
+	for _, line := range self.Lines {
+		if sameMove(line[0].Theirs, theirs) && sameMove(line[0].Ours, ours) && len(line) > 1 {
+			remaining = append(remaining, line[1:])
+		}
+	}
+	if len(remaining) < len(self.Lines) {
+		fmt.Println("dropping", len(self.Lines)-len(remaining), "conditional lines that no longer apply")
+	}
+
+	self.Lines = remaining
+	self.Ply = ply + 2
+	self.save()
+}
+
+/*
+showConditionalEditor opens a window for entering conditional lines on a copy
+of the board, set up at the position after played. Moves are made on it
+alternately for the opponent and as our reply, and saved as a line once it ends
+on a reply. It must be called on the ui thread, while waiting on the opponent.
+*/
+func showConditionalEditor(conditionals *conditionalMoves, played []chessboard.Move, isBlack bool, opponentName string) {
+	editorWindow := fyne.CurrentApp().NewWindow("Conditional Moves")
+	ctx, closeEditor := context.WithCancel(context.Background())
+
+	board := chessboard.NewChessBoard()
+	for _, mv := range played {
+		board.MovePiece(mv.From, mv.To, false)
+	}
+	board.PrepareForMove(isBlack, false)
+	board.DisableAllBtn()
+
+	ply := len(played)
+
+	//the moves entered for the line being built, theirs and ours in turn
+	var entered []chessboard.Move
+	var enteredMu sync.Mutex
+
+	statusText := widget.NewLabel("")
+	lineText := widget.NewLabel("")
+	lineText.Wrapping = fyne.TextWrapWord
+	savedList := container.NewVBox()
+
+	//shows the line being built. Runs on the ui thread.
+	showEntered := func() {
+		enteredMu.Lock()
+		defer enteredMu.Unlock()
+
+		if len(entered)%2 == 0 {
+			statusText.SetText("Play a move you expect from " + opponentName + ".")
+		} else {
+			statusText.SetText("Play your reply.")
+		}
+		names := make([]string, len(entered))
+		for i, mv := range entered {
+			names[i] = moveName(mv)
+		}
+		lineText.SetText("Line: " + strings.Join(names, ", "))
+	}
+
+	var showSaved func()
+	showSaved = func() {
+		savedList.RemoveAll()
+		linesPly, lines := conditionals.lines()
+		if linesPly != ply || len(lines) == 0 {
+			savedList.Add(widget.NewLabel("No conditional moves saved"))
+			return
+		}
+		for i, line := range lines {
+			steps := make([]string, len(line))
+			for j, step := range line {
+				steps[j] = "if " + moveName(step.Theirs) + " then " + moveName(step.Ours)
+			}
+			label := widget.NewLabel(strings.Join(steps, ", "))
+			label.Wrapping = fyne.TextWrapWord
+			savedList.Add(container.NewBorder(nil, nil, nil,
+				widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+					conditionals.remove(i)
+					showSaved()
+				}),
+				label,
+			))
+		}
+	}
+
+	//set to make the entry loop start the line again
+	restart := make(chan struct{}, 1)
+	startOver := func() {
+		select {
+		case restart <- struct{}{}:
+		default:
+		}
+	}
+
+	saveBtn := widget.NewButtonWithIcon("Save Line", theme.DocumentSaveIcon(), func() {
+		enteredMu.Lock()
+		if len(entered) == 0 || len(entered)%2 != 0 {
+			enteredMu.Unlock()
+			dialog.ShowInformation("Line not finished", "A line has to end with your reply.", editorWindow)
+			return
+		}
+		line := make([]conditionalStep, 0, len(entered)/2)
+		for i := 0; i < len(entered); i += 2 {
+			line = append(line, conditionalStep{Theirs: entered[i], Ours: entered[i+1]})
+		}
+		enteredMu.Unlock()
+
+		if err := conditionals.add(ply, line); err != nil {
+			dialog.ShowError(err, editorWindow)
+			return
+		}
+		showSaved()
+		startOver()
+	})
+	clearBtn := widget.NewButtonWithIcon("Start Over", theme.ContentUndoIcon(), startOver)
+
+	//run fn on the ui thread and wait for it, unless the window is closed first
+	doAndWait := func(fn func()) bool {
+		done := make(chan struct{})
+		fyne.Do(func() {
+			fn()
+			close(done)
+		})
+		select {
+		case <-done:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	//wait for a tile to be picked. nil means start over, or that the window closed
+	await := func(pick chan *chessboard.Location) *chessboard.Location {
+		select {
+		case l := <-pick:
+			return l
+		case <-restart:
+			//leave it set for the loop to pick up
+			startOver()
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	go func() {
+		for ctx.Err() == nil {
+			select {
+			case <-restart:
+				//take the line back off the board
+				enteredMu.Lock()
+				undo := entered
+				entered = nil
+				enteredMu.Unlock()
+				if !doAndWait(func() {
+					for i := len(undo) - 1; i >= 0; i-- {
+						board.MovePiece(undo[i].To, undo[i].From, true)
+					}
+					showEntered()
+				}) {
+					return
+				}
+			default:
+			}
+
+			enteredMu.Lock()
+			theirMove := len(entered)%2 == 0
+			enteredMu.Unlock()
+			moverIsBlack := isBlack != theirMove
+
+			//the board stays the way round it is for whoever is moving
+			var pick chan *chessboard.Location
+			if !doAndWait(func() { pick = board.PrepareForMove(moverIsBlack, moverIsBlack) }) {
+				return
+			}
+			from := await(pick)
+			if from == nil {
+				continue
+			}
+			if !doAndWait(func() { pick = board.MoveChooser(from.Rank, from.File) }) {
+				return
+			}
+			if pick == nil {
+				continue
+			}
+			to := await(pick)
+			if to == nil || (to.Rank == from.Rank && to.File == from.File) {
+				continue
+			}
+
+			move := chessboard.Move{From: from, To: to}
+			if !doAndWait(func() {
+				if board.MovePiece(from, to, false) {
+					enteredMu.Lock()
+					entered = append(entered, move)
+					enteredMu.Unlock()
+				}
+				showEntered()
+			}) {
+				return
+			}
+		}
+	}()
+
+	showEntered()
+	showSaved()
+
+	side := container.NewVBox(
+		statusText,
+		lineText,
+		container.NewHBox(clearBtn, layout.NewSpacer(), saveBtn),
+		widget.NewCard("Saved", "", savedList),
+	)
+	sideScroll := container.NewVScroll(side)
+	sideScroll.SetMinSize(fyne.NewSize(280, 0))
+	editorWindow.SetContent(container.NewBorder(nil, nil, nil, sideScroll, board.Grid))
+	editorWindow.SetOnClosed(func() {
+		closeEditor()
+		board.Close()
+	})
+	editorWindow.Resize(fyne.NewSize(850, 600))
+	editorWindow.Show()
+}
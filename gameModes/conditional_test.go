@@ -0,0 +1,123 @@
+package gameModes
+
+import (
+	"testing"
+
+	"github.com/jjj333-p/chess-fe-go/chessboard"
+)
+
+// mv makes a move from algebraic squares, like mv("e7", "e5").
+func mv(from string, to string) chessboard.Move {
+	square := func(name string) *chessboard.Location {
+		return &chessboard.Location{Rank: int(name[1] - '1'), File: int(name[0] - 'a')}
+	}
+	return chessboard.Move{From: square(from), To: square(to)}
+}
+
+/*
+twoLines prepares, after white's e4, replies to e5 with Nf3 then to Nc6 with Bb5,
+and to c5 with Nf3.
+*/
+func twoLines(t *testing.T, conditionals *conditionalMoves) {
+	t.Helper()
+	if err := conditionals.add(1, []conditionalStep{
+		{Theirs: mv("e7", "e5"), Ours: mv("g1", "f3")},
+		{Theirs: mv("b8", "c6"), Ours: mv("f1", "b5")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conditionals.add(1, []conditionalStep{
+		{Theirs: mv("c7", "c5"), Ours: mv("g1", "f3")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConditionalReplyFollowsLine(t *testing.T) {
+	conditionals := &conditionalMoves{}
+	twoLines(t, conditionals)
+
+	reply := conditionals.reply(1, mv("e7", "e5"))
+	if reply == nil || !sameMove(*reply, mv("g1", "f3")) {
+		t.Fatalf("reply to e5 is %v, want g1-f3", reply)
+	}
+	//looking up a reply changes nothing
+	if ply, lines := conditionals.lines(); ply != 1 || len(lines) != 2 {
+		t.Errorf("after reply: ply %d, %d lines", ply, len(lines))
+	}
+
+	conditionals.played(1, mv("e7", "e5"), *reply)
+	ply, lines := conditionals.lines()
+	if ply != 3 || len(lines) != 1 || !sameMove(lines[0][0].Theirs, mv("b8", "c6")) {
+		t.Fatalf("after playing the reply: ply %d, lines %v", ply, lines)
+	}
+
+	reply = conditionals.reply(3, mv("b8", "c6"))
+	if reply == nil || !sameMove(*reply, mv("f1", "b5")) {
+		t.Fatalf("reply to Nc6 is %v, want f1-b5", reply)
+	}
+	conditionals.played(3, mv("b8", "c6"), *reply)
+	if _, lines := conditionals.lines(); len(lines) != 0 {
+		t.Errorf("finished lines are kept: %v", lines)
+	}
+}
+
+func TestConditionalReplyNotPlayed(t *testing.T) {
+	conditionals := &conditionalMoves{}
+	twoLines(t, conditionals)
+
+	//they played e5, but we moved d4 by hand instead of the prepared reply
+	if reply := conditionals.reply(1, mv("e7", "e5")); reply == nil {
+		t.Fatal("no reply to e5")
+	}
+	conditionals.played(1, mv("e7", "e5"), mv("d2", "d4"))
+	if _, lines := conditionals.lines(); len(lines) != 0 {
+		t.Fatalf("lines kept after a different move: %v", lines)
+	}
+	if reply := conditionals.reply(3, mv("b8", "c6")); reply != nil {
+		t.Errorf("replied %v in a position the lines weren't for", moveName(*reply))
+	}
+}
+
+func TestConditionalReplyOtherLines(t *testing.T) {
+	conditionals := &conditionalMoves{}
+	twoLines(t, conditionals)
+
+	if reply := conditionals.reply(1, mv("d7", "d5")); reply != nil {
+		t.Errorf("replied %v to a move with no line", moveName(*reply))
+	}
+	if reply := conditionals.reply(3, mv("e7", "e5")); reply != nil {
+		t.Errorf("replied %v at the wrong move number", moveName(*reply))
+	}
+
+	//lines that had the same move from them but a different reply are dropped with the rest
+	conditionals.Lines = append(conditionals.Lines, []conditionalStep{
+		{Theirs: mv("e7", "e5"), Ours: mv("f2", "f4")},
+		{Theirs: mv("e5", "f4"), Ours: mv("g1", "f3")},
+	})
+	conditionals.played(1, mv("e7", "e5"), mv("g1", "f3"))
+	_, lines := conditionals.lines()
+	if len(lines) != 1 || !sameMove(lines[0][0].Theirs, mv("b8", "c6")) {
+		t.Errorf("lines after playing Nf3: %v", lines)
+	}
+}
+
+func TestConditionalMovesSaved(t *testing.T) {
+	dir := t.TempDir()
+	conditionals := loadConditionalMoves(dir, 12)
+	twoLines(t, conditionals)
+	conditionals.played(1, mv("e7", "e5"), mv("g1", "f3"))
+
+	loaded := loadConditionalMoves(dir, 12)
+	ply, lines := loaded.lines()
+	if ply != 3 || len(lines) != 1 {
+		t.Fatalf("loaded ply %d, lines %v", ply, lines)
+	}
+	if reply := loaded.reply(3, mv("b8", "c6")); reply == nil || !sameMove(*reply, mv("f1", "b5")) {
+		t.Errorf("loaded reply to Nc6 is %v, want f1-b5", reply)
+	}
+
+	if other := loadConditionalMoves(dir, 13); len(other.Lines) != 0 {
+		t.Errorf("another game has lines %v", other.Lines)
+	}
+}
@@ -0,0 +1,322 @@
+package gameModes
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/jjj333-p/chess-fe-go/api"
+	"sync"
+)
+
+/*
+dashboard is the window for online games. Its first tab lists the user's games,
+challenges and the games there are to watch, and every game opened from it gets
+a tab of its own, which keeps following its game while other tabs are in front.
+Everything but the background work of the games runs on the ui thread.
+*/
+type dashboard struct {
+	account AccountData
+	client  *api.Client
+	window  fyne.Window
+
+	tabs    *container.DocTabs
+	listTab *container.TabItem
+	//refreshes the list of games in the first tab
+	refreshList func()
+
+	//the games with a tab open, by game id
+	open map[int]*openGame
+	//ids of the rematches sent from finished games, whose panels see to the answers
+	rematches map[int]bool
+	//counts closed games whose background work hasn't finished yet
+	running sync.WaitGroup
+}
+
+// openGame is a game with a tab in the dashboard.
+type openGame struct {
+	tab   *container.TabItem
+	view  *gameView
+	title string
+}
+
+/*
+Games opens the dashboard of the user's online games, where they can play any
+number of them side by side, start new ones and watch other people's.
+Returns false if the games could not be loaded.
+*/
+func Games(account AccountData, client *api.Client) bool {
+	//the list and inbox stop refreshing when the window closes
+	listCtx, closeList := context.WithCancel(context.Background())
+	defer closeList()
+
+	games, err := client.CurrentGames(listCtx)
+	if err != nil {
+		fmt.Printf("Error fetching current games: %v\n", err)
+		return false
+	}
+
+	a := app.New()
+	self := &dashboard{
+		account:   account,
+		client:    client,
+		window:    a.NewWindow("Online Games"),
+		open:      make(map[int]*openGame),
+		rematches: make(map[int]bool),
+	}
+
+	self.window.SetOnClosed(closeList)
+
+	list, refreshList := self.gameList(listCtx, games)
+	self.refreshList = refreshList
+	watch := watchList(listCtx, client, account, func(game *api.DbGame) {
+		self.play(game, true)
+	})
+
+	self.listTab = container.NewTabItemWithIcon("Games", theme.HomeIcon(), container.NewAppTabs(
+		container.NewTabItem("My Games", list),
+		container.NewTabItem("Watch", watch),
+	))
+	self.tabs = container.NewDocTabs(self.listTab)
+	self.tabs.CloseIntercept = func(tab *container.TabItem) {
+		//the list stays open
+		if tab == self.listTab {
+			return
+		}
+		for gameID, game := range self.open {
+			if game.tab == tab {
+				self.close(gameID)
+				return
+			}
+		}
+		self.tabs.Remove(tab)
+	}
+
+	//keys go to whichever game is in front
+	self.window.Canvas().SetOnTypedKey(func(key *fyne.KeyEvent) {
+		for _, game := range self.open {
+			if game.tab == self.tabs.Selected() {
+				game.view.onKey(key)
+				return
+			}
+		}
+	})
+
+	self.window.SetContent(self.tabs)
+	self.window.Resize(fyne.NewSize(850, 500))
+	self.window.ShowAndRun()
+
+	//shut every game down and wait for them, so nothing touches the closed window
+	for gameID := range self.open {
+		self.close(gameID)
+	}
+	self.running.Wait()
+
+	return true
+}
+
+/*
+gameList lists the user's current games, with a row for challenging someone to
+a new one, above the inbox of challenges. The returned func reloads the games
+from the server.
+*/
+func (self *dashboard) gameList(ctx context.Context, games []api.DbGame) (fyne.CanvasObject, func()) {
+	w := self.window
+	grid := container.NewGridWithColumns(6)
+
+	userSelector, userlist, err := CreateUserSelector(ctx, self.client)
+	if err != nil {
+		fmt.Println("Error fetching users:", err)
+		userSelector = widget.NewSelect(nil, nil)
+		userSelector.PlaceHolder = "Could not load users"
+	}
+
+	inbox, refreshInbox := challengeInbox(ctx, self.client, self.account, w, func(game *api.DbGame) {
+		self.play(game, false)
+		self.refreshList()
+	}, func(challengeID int) bool {
+		return self.rematches[challengeID]
+	})
+
+	challengeBtn := widget.NewButtonWithIcon("Challenge", theme.AccountIcon(), func() {
+		selectedUsername := userSelector.Selected
+		fmt.Println("Selected User:", selectedUsername)
+		if selectedUsername == "" {
+			dialog.ShowInformation("No user selected.", "You must select a user.", w)
+			return
+		}
+
+		var selectedUserObj *api.DbUser
+		for _, user := range userlist {
+			if user.Username == selectedUsername {
+				selectedUserObj = &user
+				break
+			}
+		}
+
+		if selectedUserObj == nil {
+			dialog.ShowInformation("Error", "Selected user not found.", w)
+			return
+		}
+
+		fmt.Printf("Selected user ID: %d\n", selectedUserObj.UID)
+
+		showChallengeForm(ctx, self.client, *selectedUserObj, w, func(ch *api.Challenge) {
+			refreshInbox()
+			dialog.ShowInformation("Challenge sent",
+				"Your challenge to "+ch.ToName+" has been sent. You can start playing once they accept it.", w)
+		}, func(game *api.DbGame) {
+			self.play(game, false)
+			self.refreshList()
+		})
+	})
+
+	show := func() {
+		grid.RemoveAll()
+
+		grid.Add(widget.NewLabel("Game ID"))
+		grid.Add(widget.NewLabel("White Player"))
+		grid.Add(widget.NewLabel("Black Player"))
+		grid.Add(widget.NewLabel("Status"))
+		grid.Add(widget.NewLabel("Tournament"))
+		grid.Add(widget.NewLabel("Action"))
+
+		// Add new game row
+		grid.Add(widget.NewLabel("New:"))
+		grid.Add(widget.NewLabel(self.account.Cred.Username))
+		grid.Add(widget.NewLabel(""))
+		grid.Add(userSelector)
+		grid.Add(widget.NewLabel(""))
+		grid.Add(challengeBtn)
+
+		// Create entries for each game
+		for _, game := range games {
+			grid.Add(widget.NewLabel(fmt.Sprintf("%d", game.GameID)))
+			grid.Add(widget.NewLabel(fmt.Sprintf("%s (%d elo)", game.WhiteName, game.WhiteElo)))
+			grid.Add(widget.NewLabel(fmt.Sprintf("%s (%d elo)", game.BlackName, game.BlackElo)))
+			grid.Add(widget.NewLabel(game.Status))
+			grid.Add(widget.NewLabel(game.TName))
+			grid.Add(container.NewVBox(
+				widget.NewButton("Play Now", func() {
+					self.play(&game, false)
+				}),
+				layout.NewSpacer(),
+			))
+		}
+	}
+
+	refresh := func() {
+		go func() {
+			current, err := self.client.CurrentGames(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			fyne.Do(func() {
+				if err != nil {
+					fmt.Printf("Error fetching current games: %v\n", err)
+					return
+				}
+				games = current
+				show()
+			})
+		}()
+	}
+
+	show()
+
+	refreshBtn := widget.NewButtonWithIcon("Refresh", theme.ViewRefreshIcon(), func() {
+		refresh()
+		refreshInbox()
+	})
+
+	return container.NewVScroll(container.NewVBox(
+		widget.NewCard("Challenges", "", inbox),
+		container.NewHBox(layout.NewSpacer(), refreshBtn),
+		grid,
+	)), refresh
+}
+
+/*
+play brings a game to the front, opening a tab for it if it doesn't have one.
+spectating is for watching someone else's game.
+*/
+func (self *dashboard) play(game *api.DbGame, spectating bool) {
+	if open, isOpen := self.open[game.GameID]; isOpen {
+		self.tabs.Select(open.tab)
+		return
+	}
+
+	title := game.WhiteName + " vs " + game.BlackName
+	if !spectating {
+		if game.BlackName == self.account.Cred.Username {
+			title = "vs " + game.WhiteName
+		} else {
+			title = "vs " + game.BlackName
+		}
+	}
+
+	//registered before the view is built, so it can badge its tab straight away
+	gameID := game.GameID
+	open := &openGame{
+		tab:   container.NewTabItem(title, layout.NewSpacer()),
+		title: title,
+	}
+	self.open[gameID] = open
+
+	open.view = newGameView(self.window, self.account, self.client, game, spectating, gameHooks{
+		yourMove: func(yours bool) { self.setYourMove(gameID, yours) },
+		backToList: func() {
+			self.close(gameID)
+			self.tabs.Select(self.listTab)
+		},
+		rematchSent: func(challengeID int) { self.rematches[challengeID] = true },
+		rematch: func(next *api.DbGame) {
+			self.close(gameID)
+			self.play(next, false)
+			self.refreshList()
+		},
+		ended: func() { self.refreshList() },
+	})
+	open.tab.Content = open.view.Content
+
+	self.tabs.Append(open.tab)
+	self.tabs.Select(open.tab)
+}
+
+// close stops a game and removes its tab.
+func (self *dashboard) close(gameID int) {
+	open, isOpen := self.open[gameID]
+	if !isOpen {
+		return
+	}
+	delete(self.open, gameID)
+	open.view.close()
+	self.tabs.Remove(open.tab)
+
+	self.running.Add(1)
+	go func() {
+		defer self.running.Done()
+		open.view.wait()
+	}()
+}
+
+// setYourMove badges a game's tab while it is the user's move in it.
+func (self *dashboard) setYourMove(gameID int, yours bool) {
+	open, isOpen := self.open[gameID]
+	if !isOpen {
+		return
+	}
+	if yours {
+		open.tab.Text = open.title + " (your move)"
+		open.tab.Icon = theme.MediaRecordIcon()
+	} else {
+		open.tab.Text = open.title
+		open.tab.Icon = nil
+	}
+	self.tabs.Refresh()
+}
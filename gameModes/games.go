@@ -1,8 +1,8 @@
 package gameModes
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -11,90 +11,21 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/jjj333-p/chess-fe-go/api"
 	"github.com/jjj333-p/chess-fe-go/chessboard"
-	"io"
-	"net/http"
 	"sort"
 	"strconv"
-	"strings"
+	"sync"
 	"sync/atomic"
-	"time"
 )
 
-type Credentials struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-}
-
 type AccountData struct {
-	Cred      Credentials
-	AuthToken string
-}
-
-type DbMove struct {
-	MIndex    int    `json:"mindex"`
-	GameID    int    `json:"game_id"`
-	MFrom     int    `json:"mfrom"`
-	MTo       int    `json:"mto"`
-	PieceName string `json:"piece_name"`
-}
-
-type DbGame struct {
-	GameID         int      `json:"game_id"`
-	Date           string   `json:"date"`
-	Status         string   `json:"status"`
-	WhiteID        int      `json:"white_id"`
-	WhiteName      string   `json:"white_name"`
-	BlackID        int      `json:"black_id"`
-	BlackName      string   `json:"black_name"`
-	WhiteElo       int      `json:"white_elo"`
-	BlackElo       int      `json:"black_elo"`
-	WhiteEloChange int      `json:"white_elo_change"`
-	BlackEloChange int      `json:"black_elo_change"`
-	TID            int      `json:"tid"`
-	Bracket        int      `json:"bracket"`
-	Turn           string   `json:"turn"`
-	TName          string   `json:"tname"`
-	Moves          []DbMove `json:"moves"`
-}
-
-func fetchLastMove(gameID int, token string, serverUrl string) (*DbMove, error) {
-	url := fmt.Sprintf("%s/_game/%d/last_move", serverUrl, gameID)
-
-	fmt.Println(url)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("token", token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch last move, status code: %d", resp.StatusCode)
-	}
-
-	var move DbMove
-	err = json.NewDecoder(resp.Body).Decode(&move)
-	if err != nil {
-		return nil, err
-	}
-
-	if move.GameID == 0 {
-		return nil, nil
-	}
-
-	return &move, nil
+	Cred api.Credentials
+	// DataDir is where things kept about the user's games are saved. Empty if they can't be.
+	DataDir string
 }
 
-func dbMoveToMove(dbmove *DbMove) chessboard.Move {
+func dbMoveToMove(dbmove *api.DbMove) chessboard.Move {
 	from := &chessboard.Location{
 		Rank: dbmove.MFrom % 8,
 		File: dbmove.MFrom / 8,
@@ -109,143 +40,50 @@ func dbMoveToMove(dbmove *DbMove) chessboard.Move {
 	}
 }
 
-func makeMove(gameID int, pieceID string, from *chessboard.Location, to *chessboard.Location, token string, serverUrl string) error {
-	// Convert the locations to (x,y) tuples as expected by the server
-	fromTuple := []int{from.File, from.Rank}
-	toTuple := []int{to.File, to.Rank}
-	fmt.Println("moving request from", fromTuple, "to", toTuple)
-
-	// Create the request body
-	moveData := map[string]interface{}{
-		"piece_id": pieceID,
-		"mfrom":    fromTuple,
-		"mto":      toTuple,
-	}
-
-	// Convert the data to JSON
-	jsonData, err := json.Marshal(moveData)
-	if err != nil {
-		return fmt.Errorf("error marshaling move data: %v", err)
-	}
-
-	// Create the request
-	url := fmt.Sprintf("%s/_game/%d/move", serverUrl, gameID)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("error creating request: %v", err)
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("token", token)
-
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("error sending request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("server returned error status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	return nil
+// sameMove reports whether two moves go between the same squares.
+func sameMove(a chessboard.Move, b chessboard.Move) bool {
+	return a.From.Rank == b.From.Rank && a.From.File == b.From.File &&
+		a.To.Rank == b.To.Rank && a.To.File == b.To.File
 }
 
-type DbUser struct {
-	UID         int    `json:"uid"`
-	GamesLost   int    `json:"games_lost"`
-	GamesWon    int    `json:"games_won"`
-	GamesDraw   int    `json:"games_draw"`
-	WinStreak   int    `json:"win_streak"`
-	LoseStreak  int    `json:"lose_streak"`
-	CurrentElo  int    `json:"current_elo"`
-	TotalGames  int    `json:"total_games"`
-	Username    string `json:"username"`
-	PeakElo     int    `json:"peak_elo"`
-	PeakRank    int    `json:"peak_rank"`
-	CurrentRank int    `json:"current_rank"`
-}
-
-func GetUser(token string, serverUrl string, uid int) (*DbUser, error) {
-	url := fmt.Sprintf("%s/_user/%d", serverUrl, uid)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
-	}
-
-	req.Header.Set("token", token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("authentication required")
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server returned error status %d", resp.StatusCode)
+/*
+missedMoves reports whether moves were made between the known moves of a game,
+all of them from the first, and next. Servers number moves from 0 or 1, so
+the numbering starts from the lowest index known.
+*/
+func missedMoves(known []api.DbMove, next *api.DbMove) bool {
+	//with nothing to go on only a move numbered 0 is surely the first, and checking costs one fetch
+	if len(known) == 0 {
+		return next.MIndex > 0
 	}
-
-	var user DbUser
-	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
-		return nil, fmt.Errorf("error parsing response: %v", err)
+	lowest := known[0].MIndex
+	for _, dbmove := range known {
+		lowest = min(lowest, dbmove.MIndex)
 	}
-
-	return &user, nil
+	return next.MIndex > lowest+len(known)
 }
 
-func GetAllUsers(token string, serverUrl string) ([]DbUser, error) {
-	url := fmt.Sprintf("%s/_user/list", serverUrl)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
-	}
-
-	req.Header.Set("token", token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("authentication required")
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server returned error status %d", resp.StatusCode)
-	}
-
-	var users []DbUser
-	if err := json.NewDecoder(resp.Body).Decode(&users); err != nil {
-		return nil, fmt.Errorf("error parsing response: %v", err)
+func makeMove(ctx context.Context, client *api.Client, gameID int, pieceID string, from *chessboard.Location, to *chessboard.Location) error {
+	// Convert the locations to (x,y) tuples as expected by the server
+	moveReq := api.MoveRequest{
+		PieceID: pieceID,
+		MFrom:   [2]int{from.File, from.Rank},
+		MTo:     [2]int{to.File, to.Rank},
 	}
+	fmt.Println("moving request from", moveReq.MFrom, "to", moveReq.MTo)
 
-	return users, nil
+	return client.MakeMove(ctx, gameID, moveReq)
 }
 
-func CreateUserSelector(token string, serverUrl string) (*widget.Select, []DbUser, error) {
+func CreateUserSelector(ctx context.Context, client *api.Client) (*widget.Select, []api.DbUser, error) {
 	// Fetch all users first
-	users, err := GetAllUsers(token, serverUrl)
+	users, err := client.GetAllUsers(ctx)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to fetch users: %v", err)
 	}
 
 	// Create a map to store username to user mapping for easy lookup
-	userMap := make(map[string]*DbUser)
+	userMap := make(map[string]*api.DbUser)
 	// Create slice of usernames for the dropdown
 	usernames := make([]string, len(users))
 
@@ -267,188 +105,38 @@ func CreateUserSelector(token string, serverUrl string) (*widget.Select, []DbUse
 	return selector, users, nil
 }
 
-func CreateNewGame(token string, serverUrl string, opponentUID int) (*DbGame, error) {
-	url := fmt.Sprintf("%s/_game/new/%d", serverUrl, opponentUID)
-
-	// Create the request
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
-	}
-
-	// Set the authentication token
-	req.Header.Set("token", token)
-
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error sending request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Check response status
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("authentication required")
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("server returned error status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	// Parse the response
-	var game DbGame
-	if err := json.NewDecoder(resp.Body).Decode(&game); err != nil {
-		return nil, fmt.Errorf("error parsing response: %v", err)
-	}
-
-	return &game, nil
+// gameHooks are how a game's view tells the dashboard what happens in it. They are called on the ui thread.
+type gameHooks struct {
+	// yourMove is called whenever it becomes, or stops being, the user's move.
+	yourMove func(yours bool)
+	// backToList is for when the user is done with the finished game.
+	backToList func()
+	// rematchSent is called with the id of the challenge once a rematch has been sent.
+	rematchSent func(challengeID int)
+	// rematch is called with the new game once a rematch is accepted.
+	rematch func(next *api.DbGame)
+	// ended is called once the game is over.
+	ended func()
 }
 
-func Games(account AccountData, serverUrl string) bool {
-	// Create a new request
-	req, err := http.NewRequest("GET", serverUrl+"/_game/current", nil)
-	if err != nil {
-		fmt.Printf("Error creating request: %v\n", err)
-		return false
-	}
-
-	// Add the authentication token to headers
-	req.Header.Add("token", account.AuthToken)
-
-	// Create an HTTP client and send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("Error making request: %v\n", err)
-		return false
-	}
-	defer resp.Body.Close()
-
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Printf("Error reading response: %v\n", err)
-		return false
-	}
-
-	// Parse the JSON response
-	var games []DbGame
-	if err := json.Unmarshal(body, &games); err != nil {
-		fmt.Printf("Error parsing response: %v\n", err)
-		return false
-	}
-
-	// Create Fyne application and window
-	a := app.New()
-	w := a.NewWindow("Choose Game")
-
-	var desiredGameIDToPlay int
-
-	//init slice of length for the elements
-	gridELS := make([]fyne.CanvasObject, 0, 6*(len(games)+1))
-
-	gridELS = append(gridELS,
-		widget.NewLabel("Game ID"),
-		widget.NewLabel("White Player"),
-		widget.NewLabel("Black Player"),
-		widget.NewLabel("Status"),
-		widget.NewLabel("Tournament"),
-		widget.NewLabel("Action"),
-	)
-
-	userSelector, userlist, err := CreateUserSelector(account.AuthToken, serverUrl)
-
-	var selectedGame *DbGame
-
-	// Add new game row
-	gridELS = append(gridELS,
-		widget.NewLabel("New:"),
-		widget.NewLabel(account.Cred.Username), // Tournament placeholder
-		widget.NewLabel(""),
-		userSelector,
-		widget.NewLabel(""),
-		widget.NewButtonWithIcon("Select Opponent", theme.AccountIcon(), func() {
-			selectedUsername := userSelector.Selected
-			fmt.Println("Selected User:", selectedUsername)
-			if selectedUsername == "" {
-				dialog.ShowInformation("No user selected.", "You must select a user.", w)
-				return
-			}
-
-			var selectedUserObj *DbUser
-			for _, user := range userlist {
-				if user.Username == selectedUsername {
-					selectedUserObj = &user
-					break
-				}
-			}
-
-			if selectedUserObj == nil {
-				dialog.ShowInformation("Error", "Selected user not found.", w)
-				return
-			}
-
-			fmt.Printf("Selected user ID: %d\n", selectedUserObj.UID)
-
-			selectedGame, err = CreateNewGame(account.AuthToken, serverUrl, selectedUserObj.UID)
-			if err != nil {
-				dialog.ShowError(err, w)
-			}
-			desiredGameIDToPlay = selectedGame.GameID
-
-			w.Close()
-		}),
-	)
-
-	// Create entries for each game
-	for _, game := range games {
-		gridELS = append(gridELS,
-			widget.NewLabel(fmt.Sprintf("%d", game.GameID)),
-			widget.NewLabel(fmt.Sprintf("%s (%d elo)", game.WhiteName, game.WhiteElo)),
-			widget.NewLabel(fmt.Sprintf("%s (%d elo)", game.BlackName, game.BlackElo)),
-			widget.NewLabel(game.Status),
-			widget.NewLabel(game.TName),
-			container.NewVBox(
-				widget.NewButton("Play Now", func() {
-					desiredGameIDToPlay = game.GameID
-					w.Close()
-				}),
-				layout.NewSpacer(),
-			),
-		)
-
-	}
-
-	fmt.Println(len(gridELS))
+// gameView is an online game running in a tab of the dashboard.
+type gameView struct {
+	Content fyne.CanvasObject
 
-	//create grid with all els
-	g := container.NewGridWithColumns(6, gridELS...)
-
-	w.SetContent(container.NewVScroll(g))
-
-	w.Resize(fyne.NewSize(800, 200))
-	w.ShowAndRun()
-
-	fmt.Printf("game to play: %d\n", desiredGameIDToPlay)
-
-	if selectedGame == nil {
-		// Find the selected game
-		for _, game := range games {
-			if game.GameID == desiredGameIDToPlay {
-				gameCopy := game
-				selectedGame = &gameCopy
-				break
-			}
-		}
-	}
+	// onKey handles the keys typed while the game is in front.
+	onKey func(key *fyne.KeyEvent)
+	// close stops the game. It doesn't wait for its background work to finish, wait does.
+	close func()
+	wait  func()
+}
 
-	if selectedGame == nil && desiredGameIDToPlay == 0 {
-		return true
-	} else if selectedGame == nil {
-		panic("No game found with ID:" + strconv.Itoa(desiredGameIDToPlay))
-	}
+/*
+newGameView builds the view of an online game and starts following it, until
+it is closed. Its dialogs are shown on gameWindow. A spectating view follows the
+game live the same way, but never lets the user move, answer offers or chat.
+It must be called on the ui thread.
+*/
+func newGameView(gameWindow fyne.Window, account AccountData, client *api.Client, selectedGame *api.DbGame, spectating bool, hooks gameHooks) *gameView {
 
 	fmt.Printf("White: %s, Black: %s\n", selectedGame.WhiteName, selectedGame.BlackName)
 
@@ -457,9 +145,6 @@ func Games(account AccountData, serverUrl string) bool {
 		return dbmoves[i].MIndex < dbmoves[j].MIndex
 	})
 
-	gameApp := app.New()
-	gameWindow := gameApp.NewWindow("Online Game")
-
 	viewingHistorical := atomic.Bool{}
 	viewedMove := atomic.Int32{}
 
@@ -556,11 +241,28 @@ func Games(account AccountData, serverUrl string) bool {
 
 	topBar := container.NewHBox(playingText, layout.NewSpacer(), doublePrev, prevButton, viewingText, nextButton, doubleNext)
 
-	content := container.NewVBox(topBar, board.Grid)
+	//wired up once the game is running
+	resignBtn := widget.NewButtonWithIcon("Resign", theme.CancelIcon(), nil)
+	drawBtn := widget.NewButton("Offer Draw", nil)
+	takebackBtn := widget.NewButtonWithIcon("Request Takeback", theme.ContentUndoIcon(), nil)
+	conditionalBtn := widget.NewButton("Conditional Moves", nil)
+	actionBar := container.NewHBox(conditionalBtn, layout.NewSpacer(), takebackBtn, drawBtn, resignBtn)
+	if spectating {
+		actionBar.Hide()
+	}
 
-	gameWindow.SetContent(content)
+	//everything the game does in the background stops when this is cancelled, which happens when it ends
+	viewCtx, closeView := context.WithCancel(context.Background())
+	gameCtx, cancelGame := context.WithCancel(viewCtx)
 
-	gameWindow.Resize(fyne.NewSize(400, 400))
+	//the chat outlives the game, so players can still talk once it is over
+	chat := newChatPanel(viewCtx, client, selectedGame.GameID, account.Cred.Username, spectating)
+
+	//set up once the game is running, the clock is needed for the layout first
+	var clockFlagged func(black bool)
+	clock := newChessClock(viewCtx, selectedGame.TimeControl, func(black bool) { clockFlagged(black) })
+
+	content := container.NewBorder(nil, nil, nil, chat.Container, container.NewVScroll(container.NewVBox(topBar, clock.Container, board.Grid, actionBar)))
 
 	for _, dbmove := range dbmoves {
 		mv := dbMoveToMove(&dbmove)
@@ -570,56 +272,626 @@ func Games(account AccountData, serverUrl string) bool {
 	viewedMove.Store(int32(len(moves)))
 	updateViewingText()
 	fmt.Println(moves)
-	isBlack := selectedGame.BlackName == account.Cred.Username
-	board.PrepareForMove(isBlack, false)
-	board.DisableAllBtn()
+	//spectators see the board from white's side, which is how it starts out
+	isBlack := !spectating && selectedGame.BlackName == account.Cred.Username
+	if !spectating {
+		board.PrepareForMove(isBlack, false)
+		board.DisableAllBtn()
+	}
 	isBlackTurn := selectedGame.Turn == "B"
-	ourTurn := isBlack == isBlackTurn
+	ourTurn := !spectating && isBlack == isBlackTurn
 
 	updatePlayingText(isBlackTurn)
+	hooks.yourMove(ourTurn)
+
+	//the tab is badged while it's the user's move, but never once the game is over
+	showYourMove := func(yours bool) {
+		fyne.Do(func() {
+			if viewCtx.Err() != nil {
+				return
+			}
+			hooks.yourMove(yours && gameCtx.Err() == nil)
+		})
+	}
 
 	fmt.Println("turn", selectedGame.Turn)
 
-	gameloopRunning := atomic.Bool{}
-	gameloopRunning.Store(true)
+	//until the server's clocks are read, count from the base time
+	if len(moves) > 0 {
+		clock.Press(isBlackTurn)
+	}
+
+	var gameLoop sync.WaitGroup
+
+	gameEvents := client.WatchGame(gameCtx, selectedGame.GameID)
+
+	//run fn on the ui thread and wait for it, unless the window is closed first
+	doAndWait := func(fn func()) bool {
+		done := make(chan struct{})
+		fyne.Do(func() {
+			fn()
+			close(done)
+		})
+		select {
+		case <-done:
+			return true
+		case <-gameCtx.Done():
+			return false
+		}
+	}
+
+	//set when moves may have been taken back, so the game loop reloads the game from the server
+	resyncNeeded := make(chan struct{}, 1)
+	requestResync := func() {
+		select {
+		case resyncNeeded <- struct{}{}:
+		default:
+		}
+	}
+
+	//set when the user asks to drop the premove they have queued or are picking
+	premoveCancelled := make(chan struct{}, 1)
+	cancelPremove := func() {
+		select {
+		case premoveCancelled <- struct{}{}:
+		default:
+		}
+	}
+	onKey := func(key *fyne.KeyEvent) {}
+	if !spectating {
+		board.OnSecondaryTap = cancelPremove
+		onKey = func(key *fyne.KeyEvent) {
+			if key.Name == fyne.KeyEscape {
+				cancelPremove()
+			}
+		}
+	}
+
+	//wait for the user to pick a tile. nil means the window closed or the game needs resyncing first
+	awaitLocation := func(locationChan chan *chessboard.Location) *chessboard.Location {
+		select {
+		case l := <-locationChan:
+			return l
+		case <-resyncNeeded:
+			//leave it set for the game loop to pick up
+			requestResync()
+			return nil
+		case <-gameCtx.Done():
+			return nil
+		}
+	}
+
+	//run fn in the background, tracked so closing the window waits for it
+	background := func(fn func()) {
+		gameLoop.Add(1)
+		go func() {
+			defer gameLoop.Done()
+			fn()
+		}()
+	}
+
+	myColour, opponentName := "W", selectedGame.BlackName
+	if isBlack {
+		myColour, opponentName = "B", selectedGame.WhiteName
+	}
+
+	//show how the game finished and stop everything running in the background. Not for the ui thread.
+	var endOnce sync.Once
+	endGame := func() {
+		endOnce.Do(func() {
+			result := "The game has ended."
+			game, err := client.GetGame(gameCtx, selectedGame.GameID)
+			if gameCtx.Err() != nil {
+				return
+			}
+			if err != nil {
+				fmt.Println("error fetching finished game:", err)
+			} else {
+				result = game.Result() + "."
+			}
 
-	go func() {
+			cancelGame()
+			fyne.Do(func() {
+				if viewCtx.Err() != nil {
+					return
+				}
+				playingText.SetText(result)
+				board.DisableAllBtn()
+				board.HighlightMove(nil)
+				clock.Stop()
+				hooks.yourMove(false)
+				hooks.ended()
+
+				backToList := hooks.backToList
+
+				//the game's controls are no use now, offer what can be done next instead
+				actionBar.RemoveAll()
+				actionBar.Add(layout.NewSpacer())
+
+				if spectating {
+					actionBar.Add(widget.NewButtonWithIcon("Back to game list", theme.NavigateBackIcon(), backToList))
+					actionBar.Show()
+					dialog.ShowInformation("Game Over", result, gameWindow)
+					return
+				}
+
+				rematch, sendRematch := rematchPanel(viewCtx, client, selectedGame, isBlack, gameWindow, hooks.rematchSent, hooks.rematch)
+				actionBar.Add(rematch)
+				actionBar.Add(widget.NewButtonWithIcon("Back to game list", theme.NavigateBackIcon(), backToList))
+
+				var gameOver dialog.Dialog
+				gameOver = dialog.NewCustom("Game Over", "Close", container.NewVBox(
+					widget.NewLabel(result),
+					container.NewHBox(
+						widget.NewButton("Rematch", func() {
+							gameOver.Hide()
+							sendRematch()
+						}),
+						widget.NewButton("Back to game list", func() {
+							gameOver.Hide()
+							backToList()
+						}),
+					),
+				), gameWindow)
+				gameOver.Show()
+			})
+		})
+	}
+
+	//the clocks follow the server's when it has them, else they just keep time locally
+	serverClocks := atomic.Bool{}
+	serverClocks.Store(selectedGame.TimeControl.Base > 0)
+	syncClock := func() {
+		if !serverClocks.Load() {
+			return
+		}
+		background(func() {
+			reading, err := client.Clock(gameCtx, selectedGame.GameID)
+			if gameCtx.Err() != nil {
+				return
+			}
+			if errors.Is(err, api.ErrNotFound) {
+				fmt.Println("server has no clocks, keeping time locally")
+				serverClocks.Store(false)
+				return
+			}
+			if err != nil {
+				fmt.Println("error reading clocks:", err)
+				return
+			}
+			fyne.Do(func() { clock.Sync(reading) })
+		})
+	}
+	syncClock()
+
+	//runs on the ui thread. The server decides whether time really ran out, and ends the game if so
+	clockFlagged = func(black bool) {
+		who := "White"
+		if black {
+			who = "Black"
+		}
+		fmt.Println(who, "ran out of time")
+		if !serverClocks.Load() {
+			playingText.SetText(who + " ran out of time.")
+			return
+		}
+
+		background(func() {
+			reading, err := client.Clock(gameCtx, selectedGame.GameID)
+			if gameCtx.Err() != nil {
+				return
+			}
+			if err != nil && !errors.Is(err, api.ErrGameOver) {
+				fmt.Println("error reading clocks:", err)
+				return
+			}
+			if err == nil && reading.Running != "" {
+				//our clock ran ahead of the server's
+				fyne.Do(func() { clock.Sync(reading) })
+				return
+			}
+			endGame()
+		})
+	}
+
+	resignBtn.OnTapped = func() {
+		dialog.ShowConfirm("Resign", "Are you sure you want to resign this game?", func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			resignBtn.Disable()
+			background(func() {
+				err := client.Resign(gameCtx, selectedGame.GameID)
+				if err != nil && !errors.Is(err, api.ErrGameOver) {
+					if gameCtx.Err() == nil {
+						fyne.Do(func() {
+							resignBtn.Enable()
+							dialog.ShowError(err, gameWindow)
+						})
+					}
+					return
+				}
+				endGame()
+			})
+		}, gameWindow)
+	}
+
+	conditionals := loadConditionalMoves(account.DataDir, selectedGame.GameID)
+	conditionalBtn.OnTapped = func() {
+		//white is to move after an even number of moves
+		played := append([]chessboard.Move(nil), moves...)
+		if (len(played)%2 == 1) != isBlack {
+			dialog.ShowInformation("Your move",
+				"Conditional moves are entered while you wait for "+opponentName+" to move.", gameWindow)
+			return
+		}
+		showConditionalEditor(conditionals, played, isBlack, opponentName)
+	}
+
+	//the buttons for making each kind of offer, and their text when idle and when waiting for an answer
+	offerBtns := map[string]*widget.Button{
+		api.DrawOffer:     drawBtn,
+		api.TakebackOffer: takebackBtn,
+	}
+	offerBtnText := map[string][2]string{
+		api.DrawOffer:     {"Offer Draw", "Draw Offered"},
+		api.TakebackOffer: {"Request Takeback", "Takeback Requested"},
+	}
+	for kind, btn := range offerBtns {
+		btn.OnTapped = func() {
+			btn.Disable()
+			background(func() {
+				err := client.MakeOffer(gameCtx, selectedGame.GameID, kind)
+				if gameCtx.Err() != nil {
+					return
+				}
+				fyne.Do(func() {
+					if err != nil {
+						btn.Enable()
+						dialog.ShowError(err, gameWindow)
+						return
+					}
+					btn.SetText(offerBtnText[kind][1])
+				})
+			})
+		}
+	}
+
+	//dialogs for offers waiting on our answer, kept so they can be closed if the offer lapses
+	offerDialogs := make(map[int]dialog.Dialog)
+
+	//handleOffer runs on the ui thread
+	handleOffer := func(offer api.Offer) {
+		if offer.By == myColour {
+			btn, known := offerBtns[offer.Kind]
+			if !known {
+				return
+			}
+			switch offer.State {
+			case api.OfferPending:
+				btn.SetText(offerBtnText[offer.Kind][1])
+				btn.Disable()
+			case api.OfferDeclined:
+				btn.SetText(offerBtnText[offer.Kind][0])
+				btn.Enable()
+				dialog.ShowInformation("Offer declined", opponentName+" declined your "+offer.Kind+" offer.", gameWindow)
+			case api.OfferAccepted:
+				btn.SetText(offerBtnText[offer.Kind][0])
+				btn.Enable()
+				if offer.Kind == api.TakebackOffer {
+					requestResync()
+				}
+			}
+			return
+		}
+
+		if offer.State != api.OfferPending {
+			if d, open := offerDialogs[offer.OfferID]; open {
+				delete(offerDialogs, offer.OfferID)
+				d.Hide()
+			}
+			return
+		}
+		if _, open := offerDialogs[offer.OfferID]; open {
+			return
+		}
+
+		var title, message string
+		switch offer.Kind {
+		case api.DrawOffer:
+			title, message = "Draw offered", opponentName+" offers a draw."
+		case api.TakebackOffer:
+			title, message = "Takeback requested", opponentName+" asks to take back their last move."
+		default:
+			fmt.Println("ignoring unknown offer", offer.Kind)
+			return
+		}
+
+		d := dialog.NewConfirm(title, message, func(accept bool) {
+			//hidden because the offer lapsed, there is nothing to answer
+			if _, open := offerDialogs[offer.OfferID]; !open {
+				return
+			}
+			delete(offerDialogs, offer.OfferID)
+
+			background(func() {
+				err := client.RespondToOffer(gameCtx, selectedGame.GameID, offer.OfferID, accept)
+				if err != nil {
+					if gameCtx.Err() == nil {
+						fyne.Do(func() { dialog.ShowError(err, gameWindow) })
+					}
+					return
+				}
+				if !accept {
+					return
+				}
+				switch offer.Kind {
+				case api.DrawOffer:
+					endGame()
+				case api.TakebackOffer:
+					requestResync()
+				}
+			})
+		}, gameWindow)
+		d.SetConfirmText("Accept")
+		d.SetDismissText("Decline")
+		offerDialogs[offer.OfferID] = d
+		d.Show()
+	}
+
+	//moves and reconnects go to the game loop, offers, chat and the end of the game are handled here as they arrive
+	moveEvents := make(chan api.GameEvent)
+	background(func() {
+		defer close(moveEvents)
+
+		//queued so offers are still handled while the loop waits on the user
+		var queued []api.GameEvent
+		for {
+			var out chan api.GameEvent
+			var next api.GameEvent
+			if len(queued) > 0 {
+				out, next = moveEvents, queued[0]
+			}
+
+			select {
+			case event, ok := <-gameEvents:
+				if !ok {
+					return
+				}
+				switch {
+				case event.Offer != nil && spectating:
+					//only the players answer offers, but a takeback changes the board
+					if event.Offer.Kind == api.TakebackOffer && event.Offer.State == api.OfferAccepted {
+						requestResync()
+					}
+				case event.Offer != nil:
+					offer := *event.Offer
+					fyne.Do(func() { handleOffer(offer) })
+				case event.Chat != nil:
+					message := *event.Chat
+					fyne.Do(func() { chat.Add(message) })
+				case errors.Is(event.Err, api.ErrGameOver):
+					endGame()
+					return
+				case event.Err != nil:
+					err := event.Err
+					fyne.Do(func() {
+						dialog.ShowInformation("Error checking last move", err.Error(), gameWindow)
+					})
+					return
+				default:
+					queued = append(queued, event)
+				}
+			case out <- next:
+				queued = queued[1:]
+			case <-gameCtx.Done():
+				return
+			}
+		}
+	})
+
+	background(func() {
 		movesWeMade := make([]chessboard.Move, 0)
 		movesTheyMade := make([]chessboard.Move, 0)
 
-		lastFromRank := atomic.Int32{}
-		lastToRank := atomic.Int32{}
-		lastFromFile := atomic.Int32{}
-		lastToFile := atomic.Int32{}
+		/*
+			A premove is a move queued during the opponent's turn, played as soon as
+			their move arrives if it is still legal. premovePick is where the board
+			reports the next tile picked for it, and premoveFrom is the piece picked
+			so far.
+		*/
+		//the prepared reply to the opponent's last move, if they played into a conditional line
+		var conditionalReply *chessboard.Move
+
+		var premove *chessboard.Move
+		var premoveFrom *chessboard.Location
+		var premovePick chan *chessboard.Location
+		clearPremove := func() {
+			premove, premoveFrom, premovePick = nil, nil, nil
+			doAndWait(func() {
+				board.DisableAllBtn()
+				board.HighlightMove(nil)
+			})
+		}
 
+		//takeBack undoes the board back to the first n moves. It runs on the ui thread, as the history buttons use the same state.
+		takeBack := func(n int) {
+			viewed := int(viewedMove.Load())
+			for i := viewed; i > n; i-- {
+				board.MovePiece(moves[i-1].To, moves[i-1].From, true)
+			}
+			moves = moves[:n]
+			if viewed >= n {
+				viewedMove.Store(int32(n))
+				viewingHistorical.Store(false)
+			}
+		}
+
+		/*
+			resync fetches the whole game from the server and replays any moves we
+			missed, for when moves may have been lost while disconnected, or undoes
+			moves the server no longer has because they were taken back.
+			Returns false if the server's moves don't match the ones on our board.
+		*/
+		resync := func() bool {
+			game, err := client.GetGame(gameCtx, selectedGame.GameID)
+			if err != nil {
+				//not fatal, the next reconnect or gap will try again
+				fmt.Println("error fetching game to resync:", err)
+				return true
+			}
+
+			serverMoves := game.Moves
+			sort.Slice(serverMoves, func(i, j int) bool {
+				return serverMoves[i].MIndex < serverMoves[j].MIndex
+			})
+
+			if len(serverMoves) < len(moves) {
+				fmt.Println("server has", len(serverMoves), "moves but we have", len(moves), "taking back the rest")
+				for i := range serverMoves {
+					if !sameMove(dbMoveToMove(&serverMoves[i]), moves[i]) {
+						fmt.Println("move", i, "differs from the server")
+						return false
+					}
+				}
+				if !doAndWait(func() { takeBack(len(serverMoves)) }) {
+					return true
+				}
+				movesWeMade = movesWeMade[:0]
+			}
+
+			for i, dbmove := range serverMoves {
+				mv := dbMoveToMove(&dbmove)
+				if i < len(moves) {
+					if !sameMove(mv, moves[i]) {
+						fmt.Println("move", i, "differs from the server:", moves[i], mv)
+						return false
+					}
+					continue
+				}
+
+				fmt.Println("replaying missed move", dbmove.MIndex)
+				applied := true
+				if !viewingHistorical.Load() {
+					if !doAndWait(func() { applied = board.MovePiece(mv.From, mv.To, false) }) {
+						return true
+					}
+				}
+				if !applied {
+					return false
+				}
+				moves = append(moves, mv)
+				if !viewingHistorical.Load() {
+					viewedMove.Store(int32(len(moves)))
+				}
+			}
+
+			dbmoves = serverMoves
+			ourTurn = !spectating && isBlack == (game.Turn == "B")
+			isBlackTurn = game.Turn == "B"
+			fyne.Do(func() {
+				updatePlayingText(isBlackTurn)
+			})
+			showYourMove(ourTurn)
+			updateViewingText()
+			syncClock()
+			return true
+		}
+
+		//tell the user we can't trust the board any more, and stop playing on it
+		diverged := func() {
+			fyne.Do(func() {
+				dialog.ShowInformation("Out of sync",
+					"The board no longer matches the game on the server. Reopen the game to load it again.",
+					gameWindow)
+			})
+		}
+
+	turns:
 		for {
 
-			if !gameloopRunning.Load() {
+			if gameCtx.Err() != nil {
 				return
 			}
 
+			//moves may have been taken back, and it may not be the same player's turn any more
+			select {
+			case <-resyncNeeded:
+				fmt.Println("takeback accepted, resyncing game")
+				clearPremove()
+				if !resync() {
+					diverged()
+					return
+				}
+				continue
+			default:
+			}
+
 			var move chessboard.Move
 			if !ourTurn {
 
-				time.Sleep(1 * time.Second)
+				//wait for the server to tell us about the next move
+				var event api.GameEvent
+			waiting:
+				for {
+					//meanwhile the user can pick a premove
+					if !spectating && premove == nil && premovePick == nil {
+						if !doAndWait(func() { premovePick = board.PrepareForMove(isBlack, false) }) {
+							return
+						}
+					}
 
-				ldbm, err := fetchLastMove(selectedGame.GameID, account.AuthToken, serverUrl)
-				if err != nil {
-					fyne.Do(func() {
-						if strings.HasSuffix(err.Error(), "412") {
-							dialog.ShowInformation("Game Over", "The game has ended because someone has won.", gameWindow)
-						} else {
-							dialog.ShowInformation("Error checking last move", err.Error(), gameWindow)
+					select {
+					case ev, ok := <-moveEvents:
+						if !ok {
+							return
 						}
-						gameloopRunning.Store(false)
-					})
-					continue
+						event = ev
+						break waiting
+					case <-resyncNeeded:
+						requestResync()
+						continue turns
+					case <-premoveCancelled:
+						fmt.Println("premove cancelled")
+						clearPremove()
+					case l := <-premovePick:
+						premovePick = nil
+						switch {
+						case premoveFrom == nil:
+							premoveFrom = l
+							if !doAndWait(func() { premovePick = board.PremoveChooser(l.Rank, l.File) }) {
+								return
+							}
+							//no moves for that piece, start again
+							if premovePick == nil {
+								premoveFrom = nil
+								if !doAndWait(board.DisableAllBtn) {
+									return
+								}
+							}
+						case l.Rank == premoveFrom.Rank && l.File == premoveFrom.File:
+							//picking the same tile again cancels, like when moving
+							premoveFrom = nil
+						default:
+							premove = &chessboard.Move{From: premoveFrom, To: l}
+							premoveFrom = nil
+							fmt.Println("premove queued", premove.From, premove.To)
+							if !doAndWait(func() { board.HighlightMove(premove) }) {
+								return
+							}
+						}
+					}
 				}
-				fmt.Println("last move", ldbm)
-
-				if ldbm == nil {
+				if event.Reconnected {
+					fmt.Println("reconnected, resyncing game")
+					if !resync() {
+						diverged()
+						return
+					}
 					continue
 				}
+				ldbm := event.Move
+				fmt.Println("last move", ldbm)
 
 				//check if we already have last move
 				old := false
@@ -634,30 +906,75 @@ func Games(account AccountData, serverUrl string) bool {
 					continue
 				}
 
+				if missedMoves(dbmoves, ldbm) {
+					fmt.Println("missed moves before", ldbm.MIndex, "resyncing game")
+					if !resync() {
+						diverged()
+						return
+					}
+					continue
+				}
+
 				dbmoves = append(dbmoves, *ldbm)
 
 				move = dbMoveToMove(ldbm)
 
+				//the server also reports the move we just made, which is already on the board
+				if len(movesWeMade) > 0 && sameMove(move, movesWeMade[len(movesWeMade)-1]) {
+					fmt.Println("server confirmed our move", ldbm.MIndex)
+					continue
+				}
+
 				movesTheyMade = append(movesTheyMade, move)
+				conditionalReply = conditionals.reply(len(moves), move)
 
 				fmt.Println(*ldbm)
-				ourTurn = true
+				ourTurn = !spectating
 
 			} else {
-				if len(movesWeMade) > 0 &&
-					movesWeMade[len(movesWeMade)-1].From.File == moves[len(moves)-1].From.File &&
-					movesWeMade[len(movesWeMade)-1].From.Rank == moves[len(moves)-1].From.Rank &&
-					movesWeMade[len(movesWeMade)-1].From.Rank == moves[len(moves)-1].From.Rank &&
-					movesWeMade[len(movesWeMade)-1].From.File == moves[len(moves)-1].From.File {
-					ourTurn = false
-					continue
-
-				}
-
 				var startPosChan chan *chessboard.Location
 				var endPosChan chan *chessboard.Location
 				var startPos *chessboard.Location
 				var endPos *chessboard.Location
+
+				//a conditional reply or a premove is played straight away if it still can be, which skips picking a move below
+				if conditionalReply != nil {
+					reply := *conditionalReply
+					conditionalReply = nil
+					legal := false
+					if !viewingHistorical.Load() {
+						if !doAndWait(func() { legal = board.LegalMove(reply, isBlack) }) {
+							return
+						}
+					}
+					if legal {
+						fmt.Println("playing conditional reply", moveName(reply))
+						startPos, endPos = reply.From, reply.To
+					} else {
+						fmt.Println("conditional reply", moveName(reply), "can't be played")
+					}
+				}
+				if premove != nil && startPos == nil {
+					queued := *premove
+					cancelled := false
+					select {
+					case <-premoveCancelled:
+						cancelled = true
+					default:
+					}
+					legal := false
+					if !cancelled && !viewingHistorical.Load() {
+						if !doAndWait(func() { legal = board.LegalMove(queued, isBlack) }) {
+							return
+						}
+					}
+					if legal {
+						startPos, endPos = queued.From, queued.To
+					} else {
+						fmt.Println("discarding premove", queued.From, queued.To)
+					}
+				}
+				clearPremove()
 				//cancel op is selecting the origina tile
 				for startPos == nil ||
 					endPos == nil ||
@@ -667,14 +984,22 @@ func Games(account AccountData, serverUrl string) bool {
 					//fall back for when no options are there, nil chanel will be returned
 					for ok := true; ok; ok = endPosChan == nil {
 
-						fyne.DoAndWait(func() { startPosChan = board.PrepareForMove(isBlack, false) })
+						if !doAndWait(func() { startPosChan = board.PrepareForMove(isBlack, false) }) {
+							return
+						}
 
-						startPos = <-startPosChan
+						if startPos = awaitLocation(startPosChan); startPos == nil {
+							continue turns
+						}
 						fmt.Println(startPos, "startPos")
-						fyne.DoAndWait(func() { endPosChan = board.MoveChooser(startPos.Rank, startPos.File) })
+						if !doAndWait(func() { endPosChan = board.MoveChooser(startPos.Rank, startPos.File) }) {
+							return
+						}
 						fmt.Println(endPosChan)
 					}
-					endPos = <-endPosChan
+					if endPos = awaitLocation(endPosChan); endPos == nil {
+						continue turns
+					}
 
 					if startPos.Rank == endPos.Rank &&
 						startPos.File == endPos.File {
@@ -683,55 +1008,55 @@ func Games(account AccountData, serverUrl string) bool {
 				}
 
 				move = chessboard.Move{From: startPos, To: endPos}
+
+				//only commit the move once the server has it, so the board can't get out of sync
+				pieceType := board.Tiles[move.From.Rank][move.From.File].Piece.PieceType
+				fyne.Do(func() { playingText.SetText("Sending move...") })
+				err := submitMove(gameCtx, client, selectedGame.GameID, pieceType, move, dbmoves, func(status string) {
+					fyne.Do(func() { playingText.SetText(status) })
+				})
+				if gameCtx.Err() != nil {
+					return
+				}
+				if errors.Is(err, api.ErrGameOver) {
+					endGame()
+					return
+				}
+				if err != nil {
+					fyne.Do(func() {
+						updatePlayingText(isBlack)
+						dialog.ShowInformation("Move not accepted", err.Error(), gameWindow)
+					})
+					continue
+				}
+
 				movesWeMade = append(movesWeMade, move)
 				ourTurn = false
 
-				fmt.Println(lastFromFile.Load() == int32(move.From.File))
-				fmt.Println(lastToFile.Load() == int32(move.To.File))
-				fmt.Println(lastFromRank.Load() == int32(move.From.Rank))
-				fmt.Println(lastToRank.Load() == int32(move.To.Rank))
-
-				if lastFromFile.Load() == int32(move.From.File) &&
-					lastToFile.Load() == int32(move.To.File) &&
-					lastFromRank.Load() == int32(move.From.Rank) &&
-					lastToRank.Load() == int32(move.To.Rank) {
-					continue
+				//conditional lines only carry on if this was the reply they prepared
+				if len(moves) > 0 {
+					conditionals.played(len(moves)-1, moves[len(moves)-1], move)
 				}
-				lastFromFile.Store(int32(move.From.File))
-				lastFromRank.Store(int32(move.From.Rank))
-				lastToFile.Store(int32(move.To.File))
-				lastToRank.Store(int32(move.To.Rank))
-
-				go func() {
-
-					fmt.Println("in goroutine")
-					err = makeMove(
-						selectedGame.GameID,
-						board.Tiles[move.To.Rank][move.To.File].Piece.PieceType,
-						move.From,
-						move.To,
-						account.AuthToken,
-						serverUrl,
-					)
-					if err != nil {
-						fyne.Do(func() {
-							dialog.ShowInformation("Error making move", err.Error(), gameWindow)
-						})
-					}
-				}()
 
 			}
 
-			isBlackTurn = isBlack == ourTurn
+			//exactly one move was made, by whoever's turn it was
+			isBlackTurn = !isBlackTurn
+			blackToMove := isBlackTurn
 			fyne.Do(func() {
-				updatePlayingText(isBlackTurn)
+				updatePlayingText(blackToMove)
+				clock.Press(blackToMove)
 			})
+			showYourMove(ourTurn)
+			syncClock()
 
 			updateMoveStore := true
 			if viewingHistorical.Load() {
 				fmt.Println("Not updating grid as we are viewing historical move")
 			} else {
-				fyne.DoAndWait(func() { updateMoveStore = board.MovePiece(move.From, move.To, false) })
+				if !doAndWait(func() { updateMoveStore = board.MovePiece(move.From, move.To, false) }) {
+					return
+				}
 			}
 
 			if updateMoveStore {
@@ -744,28 +1069,37 @@ func Games(account AccountData, serverUrl string) bool {
 			fmt.Println("our turn", ourTurn)
 
 		}
-	}()
+	})
 
 	//board.PrepareForMove()
 
-	gameWindow.ShowAndRun()
-
-	gameloopRunning.Store(false)
-
-	return true
+	return &gameView{
+		Content: content,
+		onKey:   onKey,
+		close: func() {
+			closeView()
+			board.Close()
+		},
+		wait: gameLoop.Wait,
+	}
 }
 
-func Profile(account AccountData, serverUrl string) {
+func Profile(account AccountData, client *api.Client) {
 	profileApp := app.New()
 	profileWindow := profileApp.NewWindow("User Profile")
 
-	userSelector, users, err := CreateUserSelector(account.AuthToken, serverUrl)
+	//requests made for the window stop when it closes
+	ctx, closeWindow := context.WithCancel(context.Background())
+	defer closeWindow()
+	profileWindow.SetOnClosed(closeWindow)
+
+	userSelector, users, err := CreateUserSelector(ctx, client)
 	if err != nil {
 		dialog.ShowError(err, profileWindow)
 	}
 
 	var profileForm *fyne.Container
-	var selectedUserObj *DbUser
+	var selectedUserObj *api.DbUser
 
 	userSelector.OnChanged = func(s string) {
 		for _, user := range users {
@@ -0,0 +1,41 @@
+package gameModes
+
+import (
+	"testing"
+
+	"github.com/jjj333-p/chess-fe-go/api"
+)
+
+// numbered is moves with the given MIndex values.
+func numbered(indices ...int) []api.DbMove {
+	moves := make([]api.DbMove, len(indices))
+	for i, index := range indices {
+		moves[i] = api.DbMove{MIndex: index}
+	}
+	return moves
+}
+
+func TestMissedMoves(t *testing.T) {
+	tests := []struct {
+		name  string
+		known []api.DbMove
+		next  int
+		want  bool
+	}{
+		{"first move from 0", nil, 0, false},
+		{"maybe missed the first move", nil, 1, true},
+		{"missed moves before any were known", nil, 3, true},
+		{"next from 0", numbered(0, 1), 2, false},
+		{"next from 1", numbered(1, 2), 3, false},
+		{"missed one from 0", numbered(0, 1), 3, true},
+		{"missed one from 1", numbered(1, 2), 4, true},
+		{"second move from 0", numbered(0), 1, false},
+		{"missed the second move from 0", numbered(0), 2, true},
+		{"second move from 1", numbered(1), 2, false},
+	}
+	for _, tc := range tests {
+		if got := missedMoves(tc.known, &api.DbMove{MIndex: tc.next}); got != tc.want {
+			t.Errorf("%s: missedMoves(%v, %d) = %v, want %v", tc.name, tc.known, tc.next, got, tc.want)
+		}
+	}
+}
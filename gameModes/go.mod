@@ -4,6 +4,7 @@ go 1.23.8
 
 require (
 	fyne.io/fyne/v2 v2.6.0
+	github.com/jjj333-p/chess-fe-go/api v0.0.0-00010101000000-000000000000
 	github.com/jjj333-p/chess-fe-go/chessboard v0.0.0-20250505012347-32ce1f3764a0
 )
 
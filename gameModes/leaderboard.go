@@ -1,9 +1,8 @@
 package gameModes
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
 	"strconv"
 
 	"fyne.io/fyne/v2"
@@ -11,57 +10,19 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
-	"io"
+	"github.com/jjj333-p/chess-fe-go/api"
 )
 
-type LeaderboardEntry struct {
-	UID      int     `json:"uid"`
-	Username string  `json:"username"`
-	Rating   float64 `json:"rating"`
-	Rank     int     `json:"rank"`
-}
-
-func GetCurrentLeaderboard(token string, serverUrl string) ([]LeaderboardEntry, error) {
-	// Create the request
-	req, err := http.NewRequest("GET", serverUrl+"/_leaderboard/current", nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
-	}
-
-	// Set the authentication token
-	req.Header.Set("token", token)
-
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error sending request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Check response status
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("authentication required")
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("server returned error status %d: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var leaderboard []LeaderboardEntry
-	if err := json.NewDecoder(resp.Body).Decode(&leaderboard); err != nil {
-		return nil, fmt.Errorf("error parsing response: %v", err)
-	}
-
-	return leaderboard, nil
-}
-
-func Leaderboards(account AccountData, serverUrl string) {
+func Leaderboards(account AccountData, client *api.Client) {
 	leaderboardApp := app.New()
 	leaderboardWindow := leaderboardApp.NewWindow("Leaderboard")
 
-	leaderboard, err := GetCurrentLeaderboard(account.AuthToken, serverUrl)
+	//requests made for the window stop when it closes
+	ctx, closeWindow := context.WithCancel(context.Background())
+	defer closeWindow()
+	leaderboardWindow.SetOnClosed(closeWindow)
+
+	leaderboard, err := client.GetCurrentLeaderboard(ctx)
 	if err != nil {
 		dialog.ShowError(err, leaderboardWindow)
 		fmt.Printf("error getting leaderboard: %v\n", err)
@@ -0,0 +1,130 @@
+package gameModes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jjj333-p/chess-fe-go/api"
+	"github.com/jjj333-p/chess-fe-go/chessboard"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// Backoff limits for resending a move that failed to reach the server.
+const (
+	minResendDelay = 1 * time.Second
+	maxResendDelay = 30 * time.Second
+)
+
+// isTransientError reports whether a failed request is worth sending again.
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var statusErr *api.StatusError
+	if errors.As(err, &statusErr) {
+		return errors.Is(err, api.ErrServer) ||
+			statusErr.StatusCode == http.StatusRequestTimeout ||
+			statusErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	//the request itself failing is only worth retrying when the network was to blame
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
+
+/*
+moveLanded checks whether the server has moveReq among the moves after known,
+made by an earlier attempt whose response never reached us. The whole move list
+is checked, as the opponent may have replied since. known holds the moves we
+already have, so an old move with the same squares is not mistaken for it.
+*/
+func moveLanded(ctx context.Context, client *api.Client, gameID int, moveReq api.MoveRequest, known []api.DbMove) (bool, error) {
+	game, err := client.GetGame(ctx, gameID)
+	if err != nil {
+		return false, err
+	}
+
+	//-1 so a first move numbered 0 still counts as new
+	highestKnown := -1
+	for _, dbmove := range known {
+		highestKnown = max(highestKnown, dbmove.MIndex)
+	}
+
+	for _, dbmove := range game.Moves {
+		//the server numbers squares as file*8 + rank
+		if dbmove.MIndex > highestKnown &&
+			dbmove.MFrom == moveReq.MFrom[0]*8+moveReq.MFrom[1] &&
+			dbmove.MTo == moveReq.MTo[0]*8+moveReq.MTo[1] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+/*
+submitMove sends a move and only returns once the server has accepted it, it was
+rejected, or ctx is cancelled, so the caller can commit the move to the board
+knowing the server agrees. A game that has ended gives an error matching
+api.ErrGameOver.
+
+Transient failures are retried with backoff, and while the server can't be
+reached the move stays queued here. Before every resend the server's moves
+are checked against known, so a move that got through is never sent twice.
+onStatus is told what is happening so it can be shown to the user.
+*/
+func submitMove(ctx context.Context, client *api.Client, gameID int, pieceID string, move chessboard.Move, known []api.DbMove, onStatus func(string)) error {
+	delay := minResendDelay
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			landed, err := moveLanded(ctx, client, gameID, api.MoveRequest{
+				MFrom: [2]int{move.From.File, move.From.Rank},
+				MTo:   [2]int{move.To.File, move.To.Rank},
+			}, known)
+			if landed {
+				fmt.Println("move already reached the server, not sending again")
+				return nil
+			}
+			if err != nil && !isTransientError(err) {
+				return err
+			}
+		}
+
+		err := makeMove(ctx, client, gameID, pieceID, move.From, move.To)
+		if err == nil {
+			return nil
+		}
+		if !isTransientError(err) {
+			return err
+		}
+
+		var statusErr *api.StatusError
+		if errors.As(err, &statusErr) {
+			onStatus(fmt.Sprintf("Server error, resending move in %s...", delay))
+		} else {
+			onStatus(fmt.Sprintf("Offline, move queued. Retrying in %s...", delay))
+		}
+		fmt.Println("error sending move, retrying:", err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay = min(delay*2, maxResendDelay)
+	}
+}
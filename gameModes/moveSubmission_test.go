@@ -0,0 +1,172 @@
+package gameModes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/jjj333-p/chess-fe-go/api"
+	"github.com/jjj333-p/chess-fe-go/api/fakeserver"
+)
+
+func TestIsTransientError(t *testing.T) {
+	var syntaxErr *json.SyntaxError
+	if err := json.Unmarshal([]byte("{]"), &struct{}{}); !errors.As(err, &syntaxErr) {
+		t.Fatalf("no syntax error from bad json: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", &url.Error{Op: "Post", URL: "http://chess", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}, true},
+		{"connection reset", fmt.Errorf("error sending request: %w", &url.Error{Op: "Post", URL: "http://chess", Err: syscall.ECONNRESET}), true},
+		{"dns lookup", &url.Error{Op: "Get", URL: "http://chess", Err: &net.DNSError{Err: "no such host", Name: "chess"}}, true},
+		{"response cut short", fmt.Errorf("error parsing response: %w", io.ErrUnexpectedEOF), true},
+		{"server error", &api.StatusError{StatusCode: http.StatusBadGateway}, true},
+		{"too many requests", &api.StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{"request timeout", &api.StatusError{StatusCode: http.StatusRequestTimeout}, true},
+
+		{"bad json", fmt.Errorf("error parsing response: %w", syntaxErr), false},
+		{"unsupported scheme", &url.Error{Op: "Get", URL: "chess://chess", Err: errors.New(`unsupported protocol scheme "chess"`)}, false},
+		{"illegal move", &api.StatusError{StatusCode: http.StatusBadRequest}, false},
+		{"game over", &api.StatusError{StatusCode: http.StatusPreconditionFailed}, false},
+		{"logged out", &api.StatusError{StatusCode: http.StatusUnauthorized}, false},
+		{"cancelled", &url.Error{Op: "Post", URL: "http://chess", Err: context.Canceled}, false},
+		{"deadline", context.DeadlineExceeded, false},
+	}
+	for _, tc := range tests {
+		if got := isTransientError(tc.err); got != tc.want {
+			t.Errorf("%s: isTransientError(%v) = %v, want %v", tc.name, tc.err, got, tc.want)
+		}
+	}
+}
+
+// lostResponse sends requests on, but the first move it sends comes back as a dropped connection, after calling sent.
+type lostResponse struct {
+	next http.RoundTripper
+	sent func()
+	lost bool
+}
+
+func (self *lostResponse) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := self.next.RoundTrip(req)
+	if err != nil || self.lost || !strings.HasSuffix(req.URL.Path, "/move") {
+		return resp, err
+	}
+	self.lost = true
+	resp.Body.Close()
+	self.sent()
+	return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+}
+
+/*
+submissionGame starts a game on a fake server between white and black, returning
+their clients and the game.
+*/
+func submissionGame(t *testing.T) (*api.Client, *api.Client, *api.DbGame) {
+	t.Helper()
+	ctx := context.Background()
+	s := fakeserver.New()
+	t.Cleanup(s.Close)
+	white := s.AddUser("white", "pw")
+	black := s.AddUser("black", "pw")
+
+	clients := make([]*api.Client, 2)
+	for i, user := range []api.DbUser{white, black} {
+		clients[i] = api.NewClient(s.URL)
+		if err := clients[i].Login(ctx, api.Credentials{Username: user.Username, Password: "pw"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	game, err := clients[0].NewGame(ctx, black.UID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clients[0], clients[1], game
+}
+
+// logStatus is an onStatus for submitMove that only logs.
+func logStatus(t *testing.T) func(string) {
+	return func(status string) { t.Log(status) }
+}
+
+func TestSubmitMove(t *testing.T) {
+	ctx := context.Background()
+	whiteClient, blackClient, game := submissionGame(t)
+
+	if err := submitMove(ctx, whiteClient, game.GameID, "pawn", mv("e2", "e4"), nil, logStatus(t)); err != nil {
+		t.Fatal(err)
+	}
+	last, err := blackClient.LastMove(ctx, game.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.MFrom != 4*8+1 || last.MTo != 4*8+3 {
+		t.Errorf("last move %+v, want e2-e4", last)
+	}
+
+	//a move the server rejects is not sent again
+	err = submitMove(ctx, blackClient, game.GameID, "pawn", mv("e7", "e4"), []api.DbMove{*last}, func(status string) {
+		t.Errorf("retried a rejected move: %s", status)
+	})
+	if !errors.Is(err, api.ErrValidation) {
+		t.Errorf("illegal move: got %v, want ErrValidation", err)
+	}
+}
+
+func TestSubmitMoveLandedBeforeReply(t *testing.T) {
+	ctx := context.Background()
+	whiteClient, blackClient, game := submissionGame(t)
+
+	if err := submitMove(ctx, whiteClient, game.GameID, "pawn", mv("e2", "e4"), nil, logStatus(t)); err != nil {
+		t.Fatal(err)
+	}
+	current, err := blackClient.GetGame(ctx, game.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//black's move gets through but the response is lost, and white replies before black tries again
+	blackClient.HTTP.Transport = &lostResponse{next: blackClient.HTTP.Transport, sent: func() {
+		if err := whiteClient.MakeMove(ctx, game.GameID, api.MoveRequest{PieceID: "N", MFrom: [2]int{6, 0}, MTo: [2]int{5, 2}}); err != nil {
+			t.Errorf("white's reply: %v", err)
+		}
+	}}
+	if err := submitMove(ctx, blackClient, game.GameID, "pawn", mv("e7", "e5"), current.Moves, logStatus(t)); err != nil {
+		t.Fatalf("move that landed: %v", err)
+	}
+
+	got, err := blackClient.GetGame(ctx, game.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Moves) != 3 || got.Turn != "B" {
+		t.Errorf("after the lost response: turn %s, moves %+v", got.Turn, got.Moves)
+	}
+}
+
+func TestSubmitMoveGameOver(t *testing.T) {
+	ctx := context.Background()
+	whiteClient, _, game := submissionGame(t)
+
+	if err := whiteClient.Resign(ctx, game.GameID); err != nil {
+		t.Fatal(err)
+	}
+	err := submitMove(ctx, whiteClient, game.GameID, "pawn", mv("e2", "e4"), nil, func(status string) {
+		t.Errorf("retried in a finished game: %s", status)
+	})
+	if !errors.Is(err, api.ErrGameOver) {
+		t.Errorf("got %v, want ErrGameOver", err)
+	}
+}
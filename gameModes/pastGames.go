@@ -1,7 +1,7 @@
 package gameModes
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -9,54 +9,22 @@ import (
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
+	"github.com/jjj333-p/chess-fe-go/api"
 	"github.com/jjj333-p/chess-fe-go/chessboard"
-	"net/http"
 	"strconv"
 	"sync/atomic"
 )
 
-func GetOldGames(token string, serverUrl string) ([]DbGame, error) {
-	// Create the request
-	req, err := http.NewRequest("GET", serverUrl+"/_game/old", nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
-	}
-
-	// Add the authentication token
-	req.Header.Set("token", token)
-
-	// Create an HTTP client and send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Check for authentication error
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("authentication required")
-	}
-
-	// Check for other errors
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server returned error status %d", resp.StatusCode)
-	}
-
-	// Parse the response
-	var games []DbGame
-	if err := json.NewDecoder(resp.Body).Decode(&games); err != nil {
-		return nil, fmt.Errorf("error parsing response: %v", err)
-	}
-
-	return games, nil
-}
-
-func OldGames(account AccountData, serverUrl string) {
+func OldGames(account AccountData, client *api.Client) {
 	oldGamesApp := app.New()
 	oldGamesWindow := oldGamesApp.NewWindow("Past Games")
 
-	games, err := GetOldGames(account.AuthToken, serverUrl)
+	//requests made for the window stop when it closes
+	ctx, closeWindow := context.WithCancel(context.Background())
+	defer closeWindow()
+	oldGamesWindow.SetOnClosed(closeWindow)
+
+	games, err := client.OldGames(ctx)
 	if err != nil {
 		fmt.Printf("Error fetching old games: %v\n", err)
 		return
@@ -71,7 +39,7 @@ func OldGames(account AccountData, serverUrl string) {
 		widget.NewLabel("Date"),
 		widget.NewLabel("White Player"),
 		widget.NewLabel("Black Player"),
-		widget.NewLabel("Status"),
+		widget.NewLabel("Result"),
 		widget.NewLabel("Tournament"),
 		widget.NewLabel(""),
 	)
@@ -86,7 +54,7 @@ func OldGames(account AccountData, serverUrl string) {
 			widget.NewLabel(game.Date),
 			widget.NewLabel(fmt.Sprintf("%s (%d)", game.WhiteName, game.WhiteElo)),
 			widget.NewLabel(fmt.Sprintf("%s (%d)", game.BlackName, game.BlackElo)),
-			widget.NewLabel(game.Status),
+			widget.NewLabel(game.Result()),
 			widget.NewLabel(game.TName),
 			widget.NewButton("View History", func() {
 				viewGID = gID
@@ -106,7 +74,7 @@ func OldGames(account AccountData, serverUrl string) {
 		return
 	}
 
-	var selectedGame DbGame
+	var selectedGame api.DbGame
 	for _, game := range games {
 		if game.GameID == viewGID {
 			selectedGame = game
@@ -132,16 +100,7 @@ func OldGames(account AccountData, serverUrl string) {
 
 	board := chessboard.NewChessBoard()
 
-	var statusString string
-	if selectedGame.Status == "W" {
-		statusString = "White won the game."
-	} else if selectedGame.Status == "B" {
-		statusString = "Black won the game."
-	} else {
-		statusString = "Unknown status \"" + selectedGame.Status + "\""
-	}
-
-	playingText := widget.NewLabel(statusString)
+	playingText := widget.NewLabel(selectedGame.Result() + ".")
 
 	viewingText := widget.NewLabel("Viewing move 0 of 0")
 	updateViewingText := func() {
@@ -224,11 +183,17 @@ func OldGames(account AccountData, serverUrl string) {
 
 	topBar := container.NewHBox(playingText, layout.NewSpacer(), doublePrev, prevButton, viewingText, nextButton, doubleNext)
 
-	content := container.NewVBox(topBar, board.Grid)
+	//the chat is kept with the game, so it can be read back alongside the moves
+	chatCtx, closeChat := context.WithCancel(context.Background())
+	defer closeChat()
+	gameWindow.SetOnClosed(closeChat)
+	chat := newChatPanel(chatCtx, client, selectedGame.GameID, account.Cred.Username, true)
+
+	content := container.NewBorder(nil, nil, nil, chat.Container, container.NewVBox(topBar, board.Grid))
 
 	gameWindow.SetContent(content)
 
-	gameWindow.Resize(fyne.NewSize(400, 400))
+	gameWindow.Resize(fyne.NewSize(650, 400))
 
 	for _, dbmove := range selectedGame.Moves {
 		mv := dbMoveToMove(&dbmove)
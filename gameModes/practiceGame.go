@@ -1,6 +1,7 @@
 package gameModes
 
 import (
+	"context"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -8,6 +9,7 @@ import (
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
+	"github.com/jjj333-p/chess-fe-go/api"
 	"github.com/jjj333-p/chess-fe-go/chessboard"
 	"strconv"
 	"sync/atomic"
@@ -104,16 +106,62 @@ func PracticeGame() bool {
 		}
 	})
 
-	topBar := container.NewHBox(playingText, layout.NewSpacer(), doublePrev, prevButton, viewingText, nextButton, doubleNext)
+	//the clocks start after white's first move, and the time control can be picked until then
+	clockCtx, stopClock := context.WithCancel(context.Background())
+	defer stopClock()
+	//ends the move loop, once the game is over or the window closes
+	gameCtx, endGame := context.WithCancel(context.Background())
+	defer endGame()
+	gameWindow.SetOnClosed(func() {
+		stopClock()
+		endGame()
+		board.Close()
+	})
+
+	gameOver := atomic.Bool{}
+	clock := newChessClock(clockCtx, api.TimeControl{}, func(black bool) {
+		gameOver.Store(true)
+		endGame()
+		board.Close()
+		board.DisableAllBtn()
+		result := "Black ran out of time. White wins."
+		if !black {
+			result = "White ran out of time. Black wins."
+		}
+		playingText.SetText(result)
+		dialog.ShowInformation("Game Over", result, gameWindow)
+	})
+
+	timeNames := make([]string, len(timeControlPresets))
+	for i, tc := range timeControlPresets {
+		timeNames[i] = tc.String()
+	}
+	timeSelect := widget.NewSelect(timeNames, func(string) {})
+	timeSelect.SetSelectedIndex(0)
+	timeSelect.OnChanged = func(string) {
+		clock.SetTimeControl(timeControlPresets[timeSelect.SelectedIndex()])
+	}
+
+	topBar := container.NewHBox(playingText, timeSelect, layout.NewSpacer(), doublePrev, prevButton, viewingText, nextButton, doubleNext)
 
-	content := container.NewVBox(topBar, board.Grid)
+	content := container.NewVBox(topBar, clock.Container, board.Grid)
 
 	gameWindow.SetContent(content)
 
 	gameWindow.Resize(fyne.NewSize(400, 400))
 
+	//wait for a tile to be picked. nil means the game is over
+	await := func(pick chan *chessboard.Location) *chessboard.Location {
+		select {
+		case l := <-pick:
+			return l
+		case <-gameCtx.Done():
+			return nil
+		}
+	}
+
 	go func() {
-		for blackPlayer := false; true; blackPlayer = !blackPlayer {
+		for blackPlayer := false; !gameOver.Load(); blackPlayer = !blackPlayer {
 			var startPosChan chan *chessboard.Location
 			var endPosChan chan *chessboard.Location
 			var startPos *chessboard.Location
@@ -129,12 +177,18 @@ func PracticeGame() bool {
 
 					fyne.DoAndWait(func() { startPosChan = board.PrepareForMove(blackPlayer, !blackPlayer) })
 
-					startPos = <-startPosChan
+					startPos = await(startPosChan)
+					if startPos == nil {
+						return
+					}
 					fmt.Println(startPos, "startPos")
 					fyne.DoAndWait(func() { endPosChan = board.MoveChooser(startPos.Rank, startPos.File) })
 					fmt.Println(endPosChan)
 				}
-				endPos = <-endPosChan
+				endPos = await(endPosChan)
+				if endPos == nil {
+					return
+				}
 
 				if startPos.Rank == endPos.Rank &&
 					startPos.File == endPos.File {
@@ -144,6 +198,14 @@ func PracticeGame() bool {
 
 			fmt.Println(endPos.Rank, endPos.File)
 
+			if gameOver.Load() {
+				return
+			}
+			fyne.Do(func() {
+				timeSelect.Disable()
+				clock.Press(!blackPlayer)
+			})
+
 			moves = append(moves, chessboard.Move{From: startPos, To: endPos})
 			fmt.Println(len(moves), "moves", moves)
 
@@ -0,0 +1,162 @@
+package gameModes
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/jjj333-p/chess-fe-go/api"
+	"time"
+)
+
+/*
+rematchPanel offers a rematch of a finished game: a challenge to the same
+opponent with the colours swapped and the same time control and rating.
+Until ctx is cancelled it watches for the answer to our rematch, and for a
+rematch of this game sent by the opponent, which it offers to accept. Other
+challenges from the opponent are left to the inbox.
+onSent is called on the ui thread with the id of our rematch once it is sent, and
+onPlay with the new game once either rematch is accepted.
+The returned func sends our rematch, the same as the panel's button.
+*/
+func rematchPanel(ctx context.Context, client *api.Client, game *api.DbGame, isBlack bool, w fyne.Window, onSent func(challengeID int), onPlay func(*api.DbGame)) (fyne.CanvasObject, func()) {
+	opponentID, opponentName, colour := game.BlackID, game.BlackName, api.ColourBlack
+	if isBlack {
+		opponentID, opponentName, colour = game.WhiteID, game.WhiteName, api.ColourWhite
+	}
+
+	statusLabel := widget.NewLabel("")
+	statusLabel.Hide()
+	var rematchBtn *widget.Button
+	acceptBtn := widget.NewButton("Accept Rematch", nil)
+	acceptBtn.Hide()
+
+	setStatus := func(status string) {
+		statusLabel.SetText(status)
+		statusLabel.Show()
+	}
+
+	//only touched on the ui thread
+	sentID := 0
+	incomingID := 0
+
+	play := func(game *api.DbGame, err error) {
+		fyne.Do(func() {
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+			onPlay(game)
+		})
+	}
+
+	sendRematch := func() {
+		if sentID != 0 {
+			return
+		}
+		rematchBtn.Disable()
+		setStatus("Sending rematch...")
+
+		go func() {
+			ch, err := client.SendChallenge(ctx, api.ChallengeRequest{
+				ToID:        opponentID,
+				Colour:      colour,
+				TimeControl: game.TimeControl,
+				Rated:       game.Rated,
+				RematchOf:   game.GameID,
+			})
+			fyne.Do(func() {
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					rematchBtn.Enable()
+					setStatus("")
+					dialog.ShowError(fmt.Errorf("error sending rematch: %v", err), w)
+					return
+				}
+				sentID = ch.ChallengeID
+				onSent(sentID)
+				setStatus("Waiting for " + opponentName + " to accept the rematch...")
+			})
+		}()
+	}
+	rematchBtn = widget.NewButton("Rematch", sendRematch)
+
+	acceptBtn.OnTapped = func() {
+		acceptBtn.Disable()
+		challengeID := incomingID
+		go func() {
+			game, err := client.AcceptChallenge(ctx, challengeID)
+			play(game, err)
+		}()
+	}
+
+	//check on our rematch and look for theirs
+	check := func(challenges []api.Challenge) {
+		now := time.Now()
+		incoming := 0
+		for _, ch := range challenges {
+			if ch.ChallengeID == sentID && sentID != 0 {
+				switch ch.State {
+				case api.ChallengeAccepted:
+					sentID = -1
+					go func() {
+						game, err := client.GetGame(ctx, ch.GameID)
+						play(game, err)
+					}()
+				case api.ChallengeDeclined, api.ChallengeCancelled, api.ChallengeExpired:
+					setStatus(opponentName + " did not accept the rematch.")
+					//free to ask again
+					sentID = 0
+					rematchBtn.Enable()
+				}
+			}
+			if ch.FromID == opponentID && ch.RematchOf == game.GameID && ch.State == api.ChallengePending && !ch.Expired(now) {
+				incoming = ch.ChallengeID
+			}
+		}
+
+		if incoming != incomingID {
+			incomingID = incoming
+			if incoming != 0 {
+				setStatus(opponentName + " wants a rematch.")
+				acceptBtn.Enable()
+				acceptBtn.Show()
+			} else {
+				acceptBtn.Hide()
+			}
+		}
+	}
+
+	go func() {
+		for {
+			challenges, err := client.Challenges(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				fmt.Println("Error checking for a rematch:", err)
+			} else {
+				fyne.Do(func() {
+					if ctx.Err() == nil {
+						check(challenges)
+					}
+				})
+			}
+
+			select {
+			case <-time.After(challengeRefreshInterval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return container.NewHBox(statusLabel, acceptBtn, rematchBtn), sendRematch
+}
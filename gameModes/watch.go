@@ -0,0 +1,139 @@
+package gameModes
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/jjj333-p/chess-fe-go/api"
+	"sort"
+	"strings"
+)
+
+const (
+	allTournaments = "All tournaments"
+	noTournament   = "No tournament"
+)
+
+/*
+watchList lists the games being played on the server by other people, which
+can be narrowed down by player name or tournament. onWatch is called on the
+ui thread with the game picked to spectate.
+*/
+func watchList(ctx context.Context, client *api.Client, account AccountData, onWatch func(*api.DbGame)) fyne.CanvasObject {
+	playerFilter := widget.NewEntry()
+	playerFilter.SetPlaceHolder("Filter by player...")
+	tournamentFilter := widget.NewSelect([]string{allTournaments}, nil)
+	tournamentFilter.SetSelected(allTournaments)
+
+	list := container.NewVBox(widget.NewLabel("Loading games..."))
+
+	//every live game the server sent, before filtering
+	var live []api.DbGame
+
+	show := func() {
+		list.RemoveAll()
+
+		player := strings.ToLower(strings.TrimSpace(playerFilter.Text))
+		tournament := tournamentFilter.Selected
+
+		rows := []fyne.CanvasObject{
+			widget.NewLabel("Game ID"),
+			widget.NewLabel("White Player"),
+			widget.NewLabel("Black Player"),
+			widget.NewLabel("Moves"),
+			widget.NewLabel("Tournament"),
+			widget.NewLabel(""),
+		}
+		for _, game := range live {
+			if player != "" &&
+				!strings.Contains(strings.ToLower(game.WhiteName), player) &&
+				!strings.Contains(strings.ToLower(game.BlackName), player) {
+				continue
+			}
+			switch tournament {
+			case allTournaments:
+			case noTournament:
+				if game.TName != "" {
+					continue
+				}
+			default:
+				if game.TName != tournament {
+					continue
+				}
+			}
+
+			rows = append(rows,
+				widget.NewLabel(fmt.Sprintf("%d", game.GameID)),
+				widget.NewLabel(fmt.Sprintf("%s (%d elo)", game.WhiteName, game.WhiteElo)),
+				widget.NewLabel(fmt.Sprintf("%s (%d elo)", game.BlackName, game.BlackElo)),
+				widget.NewLabel(fmt.Sprintf("%d", len(game.Moves))),
+				widget.NewLabel(game.TName),
+				widget.NewButtonWithIcon("Watch", theme.VisibilityIcon(), func() {
+					onWatch(&game)
+				}),
+			)
+		}
+
+		if len(rows) == 6 {
+			list.Add(widget.NewLabel("No games to watch"))
+			return
+		}
+		list.Add(container.NewGridWithColumns(6, rows...))
+	}
+
+	refresh := func() {
+		go func() {
+			games, err := client.LiveGames(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			fyne.Do(func() {
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					fmt.Println("Error fetching live games:", err)
+					list.RemoveAll()
+					list.Add(widget.NewLabel("Could not load games to watch: " + err.Error()))
+					return
+				}
+
+				//our own games are played from the other tab
+				live = live[:0]
+				tournaments := map[string]bool{}
+				for _, game := range games {
+					if game.WhiteName == account.Cred.Username || game.BlackName == account.Cred.Username {
+						continue
+					}
+					live = append(live, game)
+					if game.TName != "" {
+						tournaments[game.TName] = true
+					}
+				}
+
+				options := []string{allTournaments, noTournament}
+				names := make([]string, 0, len(tournaments))
+				for name := range tournaments {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				tournamentFilter.Options = append(options, names...)
+				tournamentFilter.Refresh()
+
+				show()
+			})
+		}()
+	}
+
+	playerFilter.OnChanged = func(string) { show() }
+	tournamentFilter.OnChanged = func(string) { show() }
+	refreshBtn := widget.NewButtonWithIcon("Refresh", theme.ViewRefreshIcon(), refresh)
+
+	refresh()
+
+	filters := container.NewBorder(nil, nil, nil, container.NewHBox(tournamentFilter, refreshBtn), playerFilter)
+	return container.NewBorder(filters, nil, nil, nil, container.NewVScroll(list))
+}
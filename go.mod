@@ -4,6 +4,7 @@ go 1.23.8
 
 require (
 	fyne.io/fyne/v2 v2.6.0
+	github.com/jjj333-p/chess-fe-go/api v0.0.0-00010101000000-000000000000
 	github.com/jjj333-p/chess-fe-go/gameModes v0.0.0-00010101000000-000000000000
 )
 
@@ -46,3 +47,5 @@ require (
 replace github.com/jjj333-p/chess-fe-go/chessboard => ./chessboard
 
 replace github.com/jjj333-p/chess-fe-go/gameModes => ./gameModes
+
+replace github.com/jjj333-p/chess-fe-go/api => ./api
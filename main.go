@@ -1,8 +1,7 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
@@ -10,249 +9,157 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/jjj333-p/chess-fe-go/api"
+	"github.com/jjj333-p/chess-fe-go/api/replay"
 	"github.com/jjj333-p/chess-fe-go/gameModes"
-	"net/http"
 )
 
-const serverUrl = "http://66.91.172.196:5000"
-
-type loginResponse struct {
-	Token  string `json:"token"`
-	ErrStr string `json:"error"`
-}
-
-func login(creds gameModes.Credentials) loginResponse {
-	// First get the token (existing code)
-	resp, err := http.Get(serverUrl + "/request_token")
-	if err != nil {
-		fmt.Println("Error requesting token:", err)
-		return loginResponse{ErrStr: "Error requesting token"}
-	}
-	defer resp.Body.Close()
-
-	var tokenResp struct {
-		Token string `json:"token"`
-	}
-
-	err = json.NewDecoder(resp.Body).Decode(&tokenResp)
-	if err != nil {
-		fmt.Println("Error decoding response:", err)
-		return loginResponse{ErrStr: "Error requesting token"}
-	}
-
-	fmt.Println("Token:", tokenResp.Token)
+func main() {
 
-	jsonData, err := json.Marshal(creds)
-	if err != nil {
-		fmt.Println("Error marshaling credentials:", err)
-		return loginResponse{ErrStr: "Error preparing login request"}
-	}
+	config := loadConfig()
 
-	// Create authentication request
-	req, err := http.NewRequest("POST", serverUrl+"/_login/authenticate", bytes.NewBuffer(jsonData))
-	if err != nil {
-		fmt.Println("Error creating request:", err)
-		return loginResponse{ErrStr: "Error creating login request"}
+	//when recording, every client sends through the one recorder so fixtures are numbered in order
+	var recorder *replay.Transport
+	if config.recordDir != "" {
+		var err error
+		recorder, err = replay.NewRecorder(config.recordDir, api.NewClient("").HTTP.Transport)
+		if err != nil {
+			fmt.Println("Error starting recording:", err)
+		} else {
+			fmt.Println("Recording server traffic to", config.recordDir)
+		}
 	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("token", tokenResp.Token)
-
-	// Send the authentication request
-	client := &http.Client{}
-	authResp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error during authentication:", err)
-		return loginResponse{ErrStr: "Error during authentication"}
+	newClient := func(url string) *api.Client {
+		client := api.NewClient(url)
+		if recorder != nil {
+			client.HTTP.Transport = recorder
+		}
+		return client
 	}
-	defer authResp.Body.Close()
 
-	// Check response status
-	if authResp.StatusCode == 401 {
-		return loginResponse{ErrStr: "Invalid credentials"}
-	}
-	if authResp.StatusCode != 200 {
-		return loginResponse{ErrStr: fmt.Sprintf("Server error: %d", authResp.StatusCode)}
-	}
+	var account gameModes.AccountData
+	profile := config.profile(config.Selected)
+	client := newClient(profile.URL)
 
-	// Since authentication was successful, return the initial token
-	return loginResponse{Token: tokenResp.Token}
-}
+	initialChoice := 0
+	if resumeSession(config, client, &account) {
+		fmt.Println("Resumed saved session for", account.Cred.Username)
+		initialChoice = 4
+	} else {
+		initialApp := app.New()
+		initialWindow := initialApp.NewWindow("Authentication")
+
+		serverStatus := widget.NewLabel("")
+
+		//check the selected server answers, and only then run onlineFn
+		checkServer := func(onlineFn func()) {
+			url := config.profile(config.Selected).URL
+			serverStatus.SetText("Checking " + url + "...")
+			go func() {
+				err := newClient(url).Ping(context.Background())
+				fyne.Do(func() {
+					if err != nil {
+						fmt.Println("Server check failed:", err)
+						serverStatus.SetText("Server unreachable")
+						if onlineFn != nil {
+							dialog.ShowError(fmt.Errorf("cannot reach %s: %v", url, err), initialWindow)
+						}
+						return
+					}
+					serverStatus.SetText("Server online")
+					if onlineFn != nil {
+						onlineFn()
+					}
+				})
+			}()
+		}
 
-func register(creds gameModes.Credentials) loginResponse {
-	// First get the token (existing code)
-	resp, err := http.Get(serverUrl + "/request_token")
-	if err != nil {
-		fmt.Println("Error requesting token:", err)
-		return loginResponse{ErrStr: "Error requesting token"}
-	}
-	defer resp.Body.Close()
+		serverSelect := widget.NewSelect(config.profileNames(), func(name string) {
+			config.Selected = name
+			checkServer(nil)
+		})
 
-	var tokenResp struct {
-		Token string `json:"token"`
-	}
+		addServerBtn := widget.NewButtonWithIcon("", theme.ContentAddIcon(), func() {
+			nameEntry := widget.NewEntry()
+			urlEntry := widget.NewEntry()
+			urlEntry.SetPlaceHolder("http://host:5000")
+			dialog.ShowForm("Add Server", "Add", "Cancel", []*widget.FormItem{
+				widget.NewFormItem("Name", nameEntry),
+				widget.NewFormItem("URL", urlEntry),
+			}, func(ok bool) {
+				if !ok {
+					return
+				}
+				if nameEntry.Text == "" || urlEntry.Text == "" {
+					dialog.ShowError(errors.New("a server needs both a name and a url"), initialWindow)
+					return
+				}
+				if config.profile(nameEntry.Text) != nil {
+					dialog.ShowError(errors.New("there is already a server called "+nameEntry.Text), initialWindow)
+					return
+				}
+
+				config.Profiles = append(config.Profiles, serverProfile{Name: nameEntry.Text, URL: urlEntry.Text})
+				serverSelect.SetOptions(config.profileNames())
+				serverSelect.SetSelected(nameEntry.Text)
+				if err := config.save(); err != nil {
+					fmt.Println("Error saving config:", err)
+				}
+			}, initialWindow)
+		})
 
-	err = json.NewDecoder(resp.Body).Decode(&tokenResp)
-	if err != nil {
-		fmt.Println("Error decoding response:", err)
-		return loginResponse{ErrStr: "Error requesting token"}
-	}
+		serverSelect.SetSelected(config.Selected)
 
-	fmt.Println("Token:", tokenResp.Token)
+		loginchBtn := widget.NewButton("Login", func() {
+			checkServer(func() {
+				initialChoice = 1
+				initialWindow.Close()
+			})
+		})
 
-	jsonData, err := json.Marshal(creds)
-	if err != nil {
-		fmt.Println("Error marshaling credentials:", err)
-		return loginResponse{ErrStr: "Error preparing login request"}
-	}
+		registerBtn := widget.NewButton("Register", func() {
+			checkServer(func() {
+				initialChoice = 2
+				initialWindow.Close()
+			})
+		})
 
-	// Create registration request
-	req, err := http.NewRequest("POST", serverUrl+"/_login/create", bytes.NewBuffer(jsonData))
-	if err != nil {
-		fmt.Println("Error creating request:", err)
-		return loginResponse{ErrStr: "Error creating registration request"}
-	}
+		localGameBtn := widget.NewButton("Local Game", func() {
+			initialChoice = 3
+			initialWindow.Close()
+		})
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("token", tokenResp.Token)
+		initialContent := container.NewCenter(container.NewVBox(
+			layout.NewSpacer(),
+			container.NewBorder(nil, nil, nil, addServerBtn, serverSelect),
+			serverStatus,
+			loginchBtn,
+			registerBtn,
+			localGameBtn,
+			layout.NewSpacer(),
+		))
 
-	// Send the registration request
-	client := &http.Client{}
-	authResp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error during registration:", err)
-		return loginResponse{ErrStr: "Error during registration"}
-	}
-	defer authResp.Body.Close()
+		initialWindow.SetContent(initialContent)
+		initialWindow.Resize(fyne.NewSize(300, 150))
+		initialWindow.ShowAndRun()
 
-	// Check response status
-	if authResp.StatusCode == 401 {
-		return loginResponse{ErrStr: "Invalid registration"}
+		//the user may have picked a different server
+		profile = config.profile(config.Selected)
+		client = newClient(profile.URL)
 	}
-	if authResp.StatusCode != 200 {
-		return loginResponse{ErrStr: fmt.Sprintf("Server error: %d", authResp.StatusCode)}
-	}
-
-	// Since registration was successful, return the initial token
-	return loginResponse{Token: tokenResp.Token}
-}
-
-func main() {
-
-	initialChoice := 0
-	initialApp := app.New()
-	initialWindow := initialApp.NewWindow("Authentication")
-
-	loginchBtn := widget.NewButton("Login", func() {
-		initialChoice = 1
-		initialWindow.Close()
-	})
-
-	registerBtn := widget.NewButton("Register", func() {
-		initialChoice = 2
-		initialWindow.Close()
-	})
-
-	localGameBtn := widget.NewButton("Local Game", func() {
-		initialChoice = 3
-		initialWindow.Close()
-	})
-
-	initialContent := container.NewCenter(container.NewVBox(
-		layout.NewSpacer(),
-		loginchBtn,
-		registerBtn,
-		localGameBtn,
-		layout.NewSpacer(),
-	))
-
-	initialWindow.SetContent(initialContent)
-	initialWindow.Resize(fyne.NewSize(200, 100))
-	initialWindow.ShowAndRun()
-
-	var account gameModes.AccountData
 
-	loginW := func(registerInstead bool) {
-		loginApp := app.New()
-		var windowTitle string
-		if registerInstead {
-			windowTitle = "Register"
-		} else {
-			windowTitle = "Login"
+	//keep a remembered session up to date when the client has to log in again by itself
+	client.OnTokenRefresh = func(token string) {
+		session := loadSession(config)
+		if session == nil || session.ServerURL != client.BaseURL {
+			return
+		}
+		session.Token = token
+		if err := saveSession(config, session); err != nil {
+			fmt.Println("Error saving session:", err)
 		}
-		loginWindow := loginApp.NewWindow(windowTitle)
-
-		usernameEntry := widget.NewEntry()
-		usernameEntry.SetPlaceHolder("Username")
-		passwordEntry := widget.NewPasswordEntry()
-		passwordEntry.SetPlaceHolder("Password")
-
-		loadingSpinner := widget.NewProgressBarInfinite()
-		loadingSpinner.Hide()
-
-		var loginBtn *widget.Button
-		loginBtn = widget.NewButton("Login", func() {
-			if usernameEntry.Text == "" {
-				dialog.ShowError(errors.New("please enter a username"), loginWindow)
-				return
-			}
-			if passwordEntry.Text == "" {
-				dialog.ShowError(errors.New("please enter a password"), loginWindow)
-				return
-			}
-
-			loadingSpinner.Show()
-			loginBtn.Disable()
-
-			creds := gameModes.Credentials{
-				Username: usernameEntry.Text,
-				Password: passwordEntry.Text,
-			}
-			account.Cred = creds
-			var authn loginResponse
-			if registerInstead {
-				authn = register(creds)
-			} else {
-				authn = login(creds)
-			}
-
-			if authn.ErrStr != "" {
-				loadingSpinner.Hide()
-				loginBtn.Enable()
-				dialog.ShowError(errors.New(authn.ErrStr), loginWindow)
-				return
-			} else if authn.Token == "" {
-				loadingSpinner.Hide()
-				loginBtn.Enable()
-				dialog.ShowError(errors.New("empty token response from server"), loginWindow)
-				return
-			}
-
-			account.AuthToken = authn.Token
-			loadingSpinner.Hide()
-			loginBtn.Enable()
-			dialog.ShowInformation("Success", "Login successful!", loginWindow)
-
-			fmt.Println("Login successful!", account.AuthToken)
-			loginWindow.Close()
-
-		})
-
-		loginVBox := container.NewVBox(
-			layout.NewSpacer(),
-			usernameEntry,
-			passwordEntry,
-			loginBtn,
-			loadingSpinner,
-			layout.NewSpacer(),
-		)
-
-		loginWindow.SetContent(loginVBox)
-		loginWindow.Resize(fyne.NewSize(300, 200))
-		loginWindow.ShowAndRun()
 	}
 
 	// Handle the choice
@@ -261,31 +168,36 @@ func main() {
 		// Handle login
 		println("Login selected")
 
-		loginW(false)
+		authWindow(config, profile, client, &account, false)
 
 		//if the user exits dont bring up the next ui
-		if account.AuthToken == "" {
+		if client.Token() == "" {
 			return
 		}
 	case 2:
 		// Handle registration
 		println("Register selected")
 
-		loginW(true)
+		authWindow(config, profile, client, &account, true)
 
 		//if the user exits dont bring up the next ui
-		if account.AuthToken == "" {
+		if client.Token() == "" {
 			return
 		}
 	case 3:
 		gameModes.PracticeGame()
 		return
+	case 4:
+		// Logged in from the saved session
+		println("Session resumed")
 	case 0:
 		// Window was closed
 		println("Window closed")
 		return
 	}
 
+	account.DataDir = config.gameDataDir(client.BaseURL, account.Cred.Username)
+
 	for {
 		menuChoice := 0
 		menuApp := app.New()
@@ -321,6 +233,11 @@ func main() {
 			menuWindow.Close()
 		})
 
+		logOutBtn := widget.NewButton("Log Out", func() {
+			menuChoice = 7
+			menuWindow.Close()
+		})
+
 		menuContent := container.NewCenter(container.NewVBox(
 			practiceBtn,
 			onlineGameBtn,
@@ -328,6 +245,7 @@ func main() {
 			profileBtn,
 			tournamentsBtn,
 			leaderboardBtn,
+			logOutBtn,
 		))
 
 		menuWindow.SetContent(menuContent)
@@ -342,23 +260,27 @@ func main() {
 			returnToMenu = gameModes.PracticeGame()
 		case 2:
 			fmt.Println("New Online Game")
-			returnToMenu = gameModes.Games(account, serverUrl)
+			returnToMenu = gameModes.Games(account, client)
 		case 3:
 			fmt.Println("View Past Games")
-			gameModes.OldGames(account, serverUrl)
+			gameModes.OldGames(account, client)
 			returnToMenu = true
 		case 4:
 			fmt.Println("View Profile")
 			returnToMenu = true
-			gameModes.Profile(account, serverUrl)
+			gameModes.Profile(account, client)
 		case 5:
 			fmt.Println("Tournaments")
 			returnToMenu = true
-			gameModes.Tournaments(account, serverUrl)
+			gameModes.Tournaments(account, client)
 		case 6:
 			fmt.Println("Leaderboard")
 			returnToMenu = true
-			gameModes.Leaderboards(account, serverUrl)
+			gameModes.Leaderboards(account, client)
+		case 7:
+			fmt.Println("Log Out")
+			clearSession(config)
+			return
 		default:
 			panic("Unknow menu choice")
 		}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/jjj333-p/chess-fe-go/api"
+	"github.com/jjj333-p/chess-fe-go/gameModes"
+)
+
+// savedSession is a remembered login, stored next to the config file when "Remember me" is ticked.
+type savedSession struct {
+	ServerURL string `json:"server_url"`
+	Username  string `json:"username"`
+	Token     string `json:"token"`
+	// Password is only stored if the user asked for it, encrypted with the key in session.key.
+	Password string `json:"password,omitempty"`
+}
+
+func (self *clientConfig) sessionPath() string {
+	return filepath.Join(filepath.Dir(self.path), "session.json")
+}
+
+func (self *clientConfig) sessionKeyPath() string {
+	return filepath.Join(filepath.Dir(self.path), "session.key")
+}
+
+// loadSession reads the remembered login, or returns nil if there is none.
+func loadSession(config *clientConfig) *savedSession {
+	if config.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(config.sessionPath())
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Println("Error reading saved session:", err)
+		}
+		return nil
+	}
+
+	var session savedSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		fmt.Println("Error parsing saved session:", err)
+		return nil
+	}
+	return &session
+}
+
+func saveSession(config *clientConfig, session *savedSession) error {
+	if config.path == "" {
+		return errors.New("no config file location")
+	}
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(config.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(config.sessionPath(), data, 0o600)
+}
+
+// clearSession forgets the remembered login, and the key for its password, for logging out or unticking "Remember me".
+func clearSession(config *clientConfig) {
+	if config.path == "" {
+		return
+	}
+	for _, path := range []string{config.sessionPath(), config.sessionKeyPath()} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fmt.Println("Error removing saved session:", err)
+		}
+	}
+}
+
+/*
+resumeSession logs the client in from the remembered session for the selected
+server. The token is used if the server still accepts it, otherwise the
+remembered password (if any) is used to log in again. Returns false if the
+user has to log in by hand.
+*/
+func resumeSession(config *clientConfig, client *api.Client, account *gameModes.AccountData) bool {
+	session := loadSession(config)
+	if session == nil || session.ServerURL != client.BaseURL {
+		return false
+	}
+
+	creds := api.Credentials{Username: session.Username}
+	if session.Password != "" {
+		password, err := decryptPassword(config, session.Password)
+		if err != nil {
+			fmt.Println("Error decrypting saved password, forgetting it:", err)
+			forgetPassword(config, session)
+		} else {
+			creds.Password = password
+		}
+	}
+
+	client.SetToken(session.Token)
+	if err := client.VerifySession(context.Background()); err != nil {
+		fmt.Println("Saved session is no longer valid:", err)
+
+		if creds.Password == "" {
+			client.SetToken("")
+			return false
+		}
+		if err := client.Login(context.Background(), creds); err != nil {
+			fmt.Println("Error logging in with saved password:", err)
+			client.SetToken("")
+			return false
+		}
+
+		session.Token = client.Token()
+		if err := saveSession(config, session); err != nil {
+			fmt.Println("Error saving session:", err)
+		}
+	} else if creds.Password != "" {
+		client.SetCredentials(creds)
+	}
+
+	account.Cred = creds
+	return true
+}
+
+/*
+forgetPassword drops a remembered password that can't be decrypted, along with
+the key it was sealed with, so that the next "Remember me" starts afresh
+instead of failing the same way.
+*/
+func forgetPassword(config *clientConfig, session *savedSession) {
+	session.Password = ""
+	if err := saveSession(config, session); err != nil {
+		fmt.Println("Error saving session:", err)
+	}
+	if err := os.Remove(config.sessionKeyPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Println("Error removing session key:", err)
+	}
+}
+
+/*
+sessionKey loads the key used to encrypt a remembered password, creating it
+the first time. A key file that is there but not a key is an error rather than
+being replaced, as that would lose the password sealed with it.
+*/
+func sessionKey(config *clientConfig) ([]byte, error) {
+	keyPath := config.sessionKeyPath()
+
+	key, err := os.ReadFile(keyPath)
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("session key %s is %d bytes, not 32", keyPath, len(key))
+		}
+		return key, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyPath, key, 0o600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+/*
+encryptPassword seals password with AES-GCM under the session key. This keeps
+it out of the session file in plain text, but anyone who can read the config
+directory can still read the key.
+*/
+func encryptPassword(config *clientConfig, password string) (string, error) {
+	key, err := sessionKey(config)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(password), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptPassword(config *clientConfig, encrypted string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	key, err := sessionKey(config)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("saved password is too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	password, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
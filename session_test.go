@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jjj333-p/chess-fe-go/api"
+	"github.com/jjj333-p/chess-fe-go/api/fakeserver"
+	"github.com/jjj333-p/chess-fe-go/gameModes"
+)
+
+// testConfig is a config kept in a temporary directory.
+func testConfig(t *testing.T) *clientConfig {
+	return &clientConfig{path: filepath.Join(t.TempDir(), "config.json")}
+}
+
+func TestPasswordRoundTrip(t *testing.T) {
+	config := testConfig(t)
+
+	encrypted, err := encryptPassword(config, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains([]byte(encrypted), []byte("hunter2")) {
+		t.Errorf("password readable in %q", encrypted)
+	}
+	password, err := decryptPassword(config, encrypted)
+	if err != nil || password != "hunter2" {
+		t.Errorf("decrypted %q, %v", password, err)
+	}
+}
+
+func TestPasswordWrongKey(t *testing.T) {
+	config := testConfig(t)
+	encrypted, err := encryptPassword(config, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(config.sessionKeyPath(), bytes.Repeat([]byte{7}, 32), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if password, err := decryptPassword(config, encrypted); err == nil {
+		t.Errorf("decrypted %q with another key", password)
+	}
+}
+
+func TestPasswordCorrupt(t *testing.T) {
+	config := testConfig(t)
+	encrypted, err := encryptPassword(config, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flipped := bytes.Clone(sealed)
+	flipped[len(flipped)-1] ^= 1
+
+	for name, corrupt := range map[string]string{
+		"flipped bit": base64.StdEncoding.EncodeToString(flipped),
+		"cut short":   base64.StdEncoding.EncodeToString(sealed[:4]),
+		"not base64":  "not base64!",
+	} {
+		if password, err := decryptPassword(config, corrupt); err == nil {
+			t.Errorf("%s: decrypted %q", name, password)
+		}
+	}
+}
+
+func TestSessionKeyWrongLength(t *testing.T) {
+	config := testConfig(t)
+	short := []byte("too short")
+	if err := os.WriteFile(config.sessionKeyPath(), short, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := encryptPassword(config, "hunter2"); err == nil {
+		t.Error("encrypted with a key of the wrong length")
+	}
+	if key, err := os.ReadFile(config.sessionKeyPath()); err != nil || !bytes.Equal(key, short) {
+		t.Errorf("key file replaced with %x, %v", key, err)
+	}
+}
+
+/*
+rememberedLogin logs in to a fake server and remembers the session the way
+the login form does, with the password if password is set.
+*/
+func rememberedLogin(t *testing.T, config *clientConfig, password bool) *fakeserver.Server {
+	t.Helper()
+	s := fakeserver.New()
+	t.Cleanup(s.Close)
+	s.AddUser("alice", "hunter2")
+
+	client := api.NewClient(s.URL)
+	creds := api.Credentials{Username: "alice", Password: "hunter2"}
+	if err := client.Login(context.Background(), creds); err != nil {
+		t.Fatal(err)
+	}
+	session := &savedSession{ServerURL: s.URL, Username: "alice", Token: client.Token()}
+	if password {
+		encrypted, err := encryptPassword(config, creds.Password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		session.Password = encrypted
+	}
+	if err := saveSession(config, session); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestResumeSession(t *testing.T) {
+	config := testConfig(t)
+	s := rememberedLogin(t, config, true)
+
+	client := api.NewClient(s.URL)
+	account := &gameModes.AccountData{}
+	if !resumeSession(config, client, account) {
+		t.Fatal("remembered token not used")
+	}
+	if account.Cred.Username != "alice" || account.Cred.Password != "hunter2" {
+		t.Errorf("resumed as %+v", account.Cred)
+	}
+
+	//once the token expires the remembered password logs in again, and the new token is remembered
+	s.ExpireSessions()
+	client = api.NewClient(s.URL)
+	if !resumeSession(config, client, &gameModes.AccountData{}) {
+		t.Fatal("remembered password not used")
+	}
+	if session := loadSession(config); session == nil || session.Token != client.Token() {
+		t.Errorf("new token %q not saved in %+v", client.Token(), session)
+	}
+
+	//a session remembered for another server is not used
+	if resumeSession(config, api.NewClient("http://localhost:1"), &gameModes.AccountData{}) {
+		t.Error("resumed a session from another server")
+	}
+}
+
+func TestResumeSessionWrongKey(t *testing.T) {
+	config := testConfig(t)
+	s := rememberedLogin(t, config, true)
+	if err := os.WriteFile(config.sessionKeyPath(), []byte("too short"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	//the token still works, but the password that can't be read is forgotten with its key
+	if !resumeSession(config, api.NewClient(s.URL), &gameModes.AccountData{}) {
+		t.Fatal("remembered token not used")
+	}
+	if session := loadSession(config); session == nil || session.Password != "" {
+		t.Errorf("unreadable password kept in %+v", session)
+	}
+	if _, err := os.Stat(config.sessionKeyPath()); !os.IsNotExist(err) {
+		t.Errorf("bad key kept: %v", err)
+	}
+	if _, err := encryptPassword(config, "hunter2"); err != nil {
+		t.Errorf("remembering a password after: %v", err)
+	}
+
+	//without a password to fall back on an expired token means logging in by hand
+	s.ExpireSessions()
+	if resumeSession(config, api.NewClient(s.URL), &gameModes.AccountData{}) {
+		t.Error("resumed an expired session without a password")
+	}
+}